@@ -0,0 +1,139 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Counter is a Metric that represents a single numerical value that only
+// ever goes up. That implies that it cannot be used to count items whose
+// number can also go down, e.g. the number of currently running goroutines.
+// Those "counters" are represented by Gauges.
+//
+// A Counter is typically used to count requests served, tasks completed,
+// errors occurred, etc.
+//
+// To create Counter instances, use NewCounter.
+type Counter interface {
+	Metric
+	Collector
+
+	// Inc increments the counter by 1. Use Add to increment it by arbitrary
+	// non-negative values.
+	Inc()
+	// Add adds the given value to the counter. It panics if the value is <
+	// 0.
+	Add(float64)
+}
+
+// ExemplarAdder is implemented by Counters that offer the option of adding a
+// value to the Counter together with an exemplar. See ExemplarAdder in
+// metric.go for the full contract.
+//
+// Counter implements both Counter and ExemplarAdder.
+var _ ExemplarAdder = (*counter)(nil)
+
+// CounterOpts is an alias for Opts. See there for doc comments.
+type CounterOpts Opts
+
+// NewCounter creates a new Counter based on the provided CounterOpts.
+//
+// The returned implementation also implements ExemplarAdder. It is safe to
+// perform the corresponding type assertion.
+func NewCounter(opts CounterOpts) Counter {
+	desc := NewDesc(
+		BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		opts.Help,
+		nil,
+		opts.ConstLabels,
+	)
+	result := &counter{desc: desc, labelPairs: desc.constLabelPairs, createdTs: time.Now()}
+	result.Init(result) // Init self-collection.
+	return result
+}
+
+type counter struct {
+	// valBits contains the bits of the represented float64 value.
+	valBits uint64
+
+	selfCollector
+	desc *Desc
+
+	labelPairs []*dto.LabelPair
+
+	exemplar  atomic.Value // Containing nil or a *dto.Exemplar.
+	createdTs time.Time
+}
+
+func (c *counter) Desc() *Desc {
+	return c.desc
+}
+
+func (c *counter) Add(v float64) {
+	if v < 0 {
+		panic(errors.New("counter cannot decrease in value"))
+	}
+	for {
+		oldBits := atomic.LoadUint64(&c.valBits)
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + v)
+		if atomic.CompareAndSwapUint64(&c.valBits, oldBits, newBits) {
+			return
+		}
+	}
+}
+
+func (c *counter) AddWithExemplar(v float64, e Labels) {
+	c.Add(v)
+	c.updateExemplar(v, e)
+}
+
+func (c *counter) Inc() {
+	c.Add(1)
+}
+
+func (c *counter) Write(out *dto.Metric) error {
+	val := math.Float64frombits(atomic.LoadUint64(&c.valBits))
+	var exemplar *dto.Exemplar
+	if e := c.exemplar.Load(); e != nil {
+		exemplar = e.(*dto.Exemplar)
+	}
+	out.Label = c.labelPairs
+	out.Counter = &dto.Counter{
+		Value:    proto64(val),
+		Exemplar: exemplar,
+		CreatedTimestamp: &tspb.Timestamp{
+			Seconds: c.createdTs.Unix(),
+			Nanos:   int32(c.createdTs.Nanosecond()),
+		},
+	}
+	return nil
+}
+
+func (c *counter) updateExemplar(v float64, l Labels) {
+	if l == nil {
+		return
+	}
+	e, err := newExemplar(v, time.Now(), l)
+	if err != nil {
+		panic(err)
+	}
+	c.exemplar.Store(e)
+}
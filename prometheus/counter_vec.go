@@ -0,0 +1,131 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"sync"
+	"time"
+)
+
+// CounterVec is a Collector that bundles a set of Counters that all share the
+// same Desc, but have different values for their variable labels. This is
+// used if you want to count the same thing partitioned by various
+// dimensions, e.g. the number of HTTP requests, partitioned by response code
+// and method.
+//
+// Create instances with NewCounterVec.
+type CounterVec struct {
+	desc *Desc
+
+	mtx      sync.RWMutex
+	children map[uint64]*counter
+}
+
+// NewCounterVec creates a new CounterVec based on the provided CounterOpts
+// and partitioned by the given label names.
+func NewCounterVec(opts CounterOpts, labelNames []string) *CounterVec {
+	desc := NewDesc(
+		BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		opts.Help,
+		labelNames,
+		opts.ConstLabels,
+	)
+	return &CounterVec{
+		desc:     desc,
+		children: map[uint64]*counter{},
+	}
+}
+
+// GetMetricWithLabelValues returns the Counter for the given slice of label
+// values (same order as the variable labels in Desc). If that combination of
+// label values is accessed for the first time, a new Counter is created.
+func (v *CounterVec) GetMetricWithLabelValues(lvs ...string) (Counter, error) {
+	c, err := v.getOrCreate(lvs...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetMetricWith works like GetMetricWithLabelValues but takes Labels instead
+// of a slice of label values, looking up each variable label by name.
+func (v *CounterVec) GetMetricWith(labels Labels) (Counter, error) {
+	lvs := make([]string, len(v.desc.variableLabels))
+	for i, name := range v.desc.variableLabels {
+		lvs[i] = labels[name]
+	}
+	return v.GetMetricWithLabelValues(lvs...)
+}
+
+// With works like GetMetricWith, but panics where GetMetricWith would have
+// returned an error.
+func (v *CounterVec) With(labels Labels) Counter {
+	c, err := v.GetMetricWith(labels)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// WithLabelValues works like GetMetricWithLabelValues, but panics where
+// GetMetricWithLabelValues would have returned an error.
+func (v *CounterVec) WithLabelValues(lvs ...string) Counter {
+	c, err := v.GetMetricWithLabelValues(lvs...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (v *CounterVec) getOrCreate(lvs ...string) (*counter, error) {
+	if err := validateLabelValues(lvs, len(v.desc.variableLabels)); err != nil {
+		return nil, err
+	}
+	h := hashNew()
+	for _, lv := range lvs {
+		h = hashAdd(h, lv)
+		h = hashAddByte(h, separatorByte)
+	}
+
+	v.mtx.RLock()
+	c, ok := v.children[h]
+	v.mtx.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if c, ok := v.children[h]; ok {
+		return c, nil
+	}
+	c = &counter{desc: v.desc, labelPairs: makeLabelPairs(v.desc, lvs), createdTs: time.Now()}
+	c.Init(c)
+	v.children[h] = c
+	return c, nil
+}
+
+// Describe implements Collector.
+func (v *CounterVec) Describe(ch chan<- *Desc) {
+	ch <- v.desc
+}
+
+// Collect implements Collector.
+func (v *CounterVec) Collect(ch chan<- Metric) {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+	for _, c := range v.children {
+		ch <- c
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Desc is the descriptor used by every Prometheus Metric. It is essentially
+// the immutable meta-data of a Metric. The normal Metric implementations
+// included in this package manage their Desc themselves. Users of custom
+// Metric implementations are responsible for providing a Desc upon
+// instantiation.
+//
+// Descriptors registered with the same registry have to fulfill certain
+// consistency and uniqueness criteria if they share the same fully-qualified
+// name: They must have the same help string and the same label names (aka
+// label dimensions) in each, constLabels and variableLabels, but they must
+// differ in the values of the constLabels.
+//
+// Descriptors that share the same fully-qualified names and the same label
+// values of their constLabels are considered equal.
+//
+// Use NewDesc to create new Desc instances.
+type Desc struct {
+	// fqName has been built from Namespace, Subsystem, and Name.
+	fqName string
+	// help provides some helpful information about this metric.
+	help string
+	// constLabelPairs contains precalculated DTO label pairs based on
+	// the constant labels.
+	constLabelPairs []*dto.LabelPair
+	// variableLabels contains names of labels for which the metric
+	// maintains variable values.
+	variableLabels []string
+	// id is a hash of the values of the ConstLabels and fqName. This
+	// must be unique among all registered descriptors and can be used as
+	// an identifier of the descriptor.
+	id uint64
+	// dimHash is a hash of the label names (preset and variable) and the
+	// Help string. Each Desc with the same fqName must have the same
+	// dimHash.
+	dimHash uint64
+	// err is an error that occurred during construction. It is reported
+	// on registration time.
+	err error
+}
+
+// NewDesc allocates and initializes a new Desc. Errors are recorded in the
+// Desc and will be reported on registration.
+//
+// variableLabels and constLabels can be nil if no such labels should be set.
+// fqName must not be empty.
+//
+// variableLabels only contain the label names. Their label values are
+// variable and therefore not part of the Desc. (They are managed within the
+// Metric.)
+//
+// For constLabels, the label values are constant. Therefore, they are fully
+// specified in the Desc. See the Opts documentation for the implications of
+// constant labels.
+func NewDesc(fqName, help string, variableLabels []string, constLabels Labels) *Desc {
+	d := &Desc{
+		fqName:         fqName,
+		help:           help,
+		variableLabels: variableLabels,
+	}
+	if help == "" {
+		d.err = errors.New("empty help string")
+		return d
+	}
+	if !labelNameRegexp.MatchString(fqName) {
+		d.err = fmt.Errorf("%q is not a valid metric name", fqName)
+		return d
+	}
+	labelValues := make([]string, 1, len(constLabels)+1)
+	labelValues[0] = help
+	labelNames := make([]string, 0, len(constLabels)+len(variableLabels))
+	labelNameSet := map[string]struct{}{}
+	for labelName, labelValue := range constLabels {
+		if !checkLabelName(labelName) {
+			d.err = fmt.Errorf("%q is not a valid label name for metric %q", labelName, fqName)
+			return d
+		}
+		labelValues = append(labelValues, labelValue)
+		labelNames = append(labelNames, labelName)
+		labelNameSet[labelName] = struct{}{}
+	}
+	sort.Strings(labelNames)
+	for _, labelName := range variableLabels {
+		if !checkLabelName(labelName) {
+			d.err = fmt.Errorf("%q is not a valid label name for metric %q", labelName, fqName)
+			return d
+		}
+		if _, ok := labelNameSet[labelName]; ok {
+			d.err = fmt.Errorf("duplicate label name %q for metric %q", labelName, fqName)
+			return d
+		}
+		labelNameSet[labelName] = struct{}{}
+	}
+
+	vh := hashNew()
+	vh = hashAdd(vh, help)
+	vh = hashAddByte(vh, separatorByte)
+	for _, labelName := range labelNames {
+		vh = hashAdd(vh, labelName)
+		vh = hashAddByte(vh, separatorByte)
+	}
+	for _, labelName := range variableLabels {
+		vh = hashAdd(vh, labelName)
+		vh = hashAddByte(vh, separatorByte)
+	}
+	d.dimHash = vh
+
+	d.constLabelPairs = make([]*dto.LabelPair, 0, len(constLabels))
+	for n, v := range constLabels {
+		n, v := n, v
+		d.constLabelPairs = append(d.constLabelPairs, &dto.LabelPair{
+			Name:  &n,
+			Value: &v,
+		})
+	}
+	sort.Sort(labelPairSorter(d.constLabelPairs))
+
+	xxh := hashNew()
+	xxh = hashAdd(xxh, fqName)
+	xxh = hashAddByte(xxh, separatorByte)
+	for _, lp := range d.constLabelPairs {
+		xxh = hashAdd(xxh, lp.GetName())
+		xxh = hashAddByte(xxh, separatorByte)
+		xxh = hashAdd(xxh, lp.GetValue())
+		xxh = hashAddByte(xxh, separatorByte)
+	}
+	d.id = xxh
+
+	return d
+}
+
+// NewInvalidDesc returns an invalid descriptor, i.e. a descriptor with the
+// provided error set. If a collector returning such a descriptor is
+// registered, registration will fail with the provided error.
+func NewInvalidDesc(err error) *Desc {
+	return &Desc{err: err}
+}
+
+func (d *Desc) String() string {
+	lpStrings := make([]string, 0, len(d.constLabelPairs))
+	for _, lp := range d.constLabelPairs {
+		lpStrings = append(lpStrings, fmt.Sprintf("%s=%q", lp.GetName(), lp.GetValue()))
+	}
+	return fmt.Sprintf(
+		"Desc{fqName: %q, help: %q, constLabels: {%s}, variableLabels: %v}",
+		d.fqName,
+		d.help,
+		strings.Join(lpStrings, ","),
+		d.variableLabels,
+	)
+}
+
+func checkLabelName(l string) bool {
+	return labelNameRegexp.MatchString(l) && !strings.HasPrefix(l, reservedLabelPrefix)
+}
+
+type labelPairSorter []*dto.LabelPair
+
+func (s labelPairSorter) Len() int           { return len(s) }
+func (s labelPairSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s labelPairSorter) Less(i, j int) bool { return s[i].GetName() < s[j].GetName() }
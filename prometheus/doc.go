@@ -18,45 +18,44 @@
 // All exported functions and methods are safe to be used concurrently unless
 // specified otherwise.
 //
-// A Basic Example
+// # A Basic Example
 //
 // As a starting point, a very basic usage example:
 //
-//    package main
-//
-//    import (
-//    	"net/http"
-//
-//    	"github.com/prometheus/client_golang/prometheus"
-//    )
-//
-//    var (
-//    	cpuTemp = prometheus.NewGauge(prometheus.GaugeOpts{
-//    		Name: "cpu_temperature_celsius",
-//    		Help: "Current temperature of the CPU.",
-//    	})
-//    	hdFailures = prometheus.NewCounter(prometheus.CounterOpts{
-//    		Name: "hd_errors_total",
-//    		Help: "Number of hard-disk errors.",
-//    	})
-//    )
-//
-//    func init() {
-//    	// Metrics have to be registered to be exposed:
-//    	prometheus.MustRegister(cpuTemp)
-//    	prometheus.MustRegister(hdFailures)
-//    }
-//
-//    func main() {
-//    	cpuTemp.Set(65.3)
-//    	hdFailures.Inc()
-//
-//    	// The Handler function provides a default handler to expose metrics
-//    	// via an HTTP server. "/metrics" is the usual endpoint for that.
-//    	http.Handle("/metrics", prometheus.Handler())
-//    	http.ListenAndServe(":8080", nil)
-//    }
-//
+//	package main
+//
+//	import (
+//		"net/http"
+//
+//		"github.com/prometheus/client_golang/prometheus"
+//	)
+//
+//	var (
+//		cpuTemp = prometheus.NewGauge(prometheus.GaugeOpts{
+//			Name: "cpu_temperature_celsius",
+//			Help: "Current temperature of the CPU.",
+//		})
+//		hdFailures = prometheus.NewCounter(prometheus.CounterOpts{
+//			Name: "hd_errors_total",
+//			Help: "Number of hard-disk errors.",
+//		})
+//	)
+//
+//	func init() {
+//		// Metrics have to be registered to be exposed:
+//		prometheus.MustRegister(cpuTemp)
+//		prometheus.MustRegister(hdFailures)
+//	}
+//
+//	func main() {
+//		cpuTemp.Set(65.3)
+//		hdFailures.Inc()
+//
+//		// The Handler function provides a default handler to expose metrics
+//		// via an HTTP server. "/metrics" is the usual endpoint for that.
+//		http.Handle("/metrics", prometheus.Handler())
+//		http.ListenAndServe(":8080", nil)
+//	}
 //
 // This is a complete program that exports two metrics, a Gauge and a Counter.
 // It also exports some stats about the HTTP usage of the /metrics
@@ -118,11 +117,17 @@
 // The functions Handler and UninstrumentedHandler create an HTTP handler to
 // serve metrics from the default registry in the default way, which covers most
 // of the use cases. With HandlerFor, you can create a custom HTTP handler for
-// custom registries.
+// custom registries. Both negotiate the wire format with the client based on
+// its Accept header, and both understand the classic Prometheus text format,
+// the protobuf delimited format, and the OpenMetrics 1.0 text format.
 //
 // The functions Push and PushAdd push the metrics from the default registry via
-// HTTP to a Pushgateway. With PushFrom and PushAddFrom, you can push the
-// metrics from custom registries. However, often you just want to push a
-// handfull of Collectors only. For that case, there are the convenience
-// functions PushCollectors and PushAddCollectors.
+// HTTP to a Pushgateway. However, often you just want to push a handfull of
+// Collectors only. For that case, there are the convenience functions
+// PushCollectors and PushAddCollectors.
+//
+// Deprecated: Push, PushAdd, PushCollectors, and PushAddCollectors are
+// retained for backwards compatibility. The push subpackage offers a more
+// flexible builder-style Pusher (custom HTTP client, TLS, authentication,
+// retries, and arbitrary grouping labels).
 package prometheus
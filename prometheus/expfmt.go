@@ -0,0 +1,374 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Format is a MIME type describing a particular wire representation that
+// metrics can be exposed in.
+type Format string
+
+// Constants for the different exposition formats understood by Handler and
+// HandlerFor.
+const (
+	FmtUnknown     Format = `<unknown>`
+	FmtText        Format = `text/plain; version=0.0.4`
+	FmtProtoDelim  Format = `application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited`
+	FmtOpenMetrics Format = `application/openmetrics-text; version=1.0.0; charset=utf-8`
+)
+
+const (
+	hdrAccept      = "Accept"
+	hdrContentType = "Content-Type"
+)
+
+// Negotiate inspects the Accept header of a request and returns the
+// resulting format to be used for content encoding. It implements the
+// content negotiation rules used by Handler and HandlerFor: the first
+// accepted MIME type (in order of preference given by the client) that this
+// package knows how to produce wins; if none is found (or no Accept header
+// is present at all), it falls back to the classic Prometheus text format.
+func Negotiate(h http.Header) Format {
+	for _, ac := range strings.Split(h.Get(hdrAccept), ",") {
+		mediatype, params, err := mime.ParseMediaType(ac)
+		if err != nil {
+			continue
+		}
+		switch mediatype {
+		case "application/openmetrics-text":
+			if params["version"] == "" || params["version"] == "1.0.0" || params["version"] == "0.0.1" {
+				return FmtOpenMetrics
+			}
+		case "application/vnd.google.protobuf":
+			if params["proto"] == "io.prometheus.client.MetricFamily" && params["encoding"] == "delimited" {
+				return FmtProtoDelim
+			}
+		case "text/plain":
+			if params["version"] == "0.0.4" || params["version"] == "" {
+				return FmtText
+			}
+		}
+	}
+	return FmtText
+}
+
+// Encoder encodes a stream of MetricFamily protobufs into a particular
+// exposition format and writes it to an underlying io.Writer. Close must be
+// called once all MetricFamilies have been encoded; for formats that
+// require a trailer (e.g. the "# EOF" marker mandated by OpenMetrics),
+// Close is where it is written.
+type Encoder interface {
+	Encode(*dto.MetricFamily) error
+	Close() error
+}
+
+// NewEncoder returns a new Encoder that writes to w in the given format.
+func NewEncoder(w io.Writer, format Format) Encoder {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FmtProtoDelim:
+		return &protoEncoder{w: bw}
+	case FmtOpenMetrics:
+		return &openMetricsEncoder{w: bw}
+	default:
+		return &textEncoder{w: bw}
+	}
+}
+
+// protoEncoder writes length-delimited protobuf messages, one per
+// MetricFamily, as used by the `encoding=delimited` protobuf format.
+type protoEncoder struct {
+	w *bufio.Writer
+}
+
+func (e *protoEncoder) Encode(mf *dto.MetricFamily) error {
+	buf, err := proto.Marshal(mf)
+	if err != nil {
+		return err
+	}
+	var lenBuf [binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(buf)))
+	if _, err := e.w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err = e.w.Write(buf)
+	return err
+}
+
+func (e *protoEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// textEncoder writes the classic Prometheus text exposition format
+// (version 0.0.4).
+type textEncoder struct {
+	w *bufio.Writer
+}
+
+func (e *textEncoder) Encode(mf *dto.MetricFamily) error {
+	fmt.Fprintf(e.w, "# HELP %s %s\n", mf.GetName(), escapeString(mf.GetHelp(), false))
+	fmt.Fprintf(e.w, "# TYPE %s %s\n", mf.GetName(), strings.ToLower(mf.GetType().String()))
+	for _, m := range mf.Metric {
+		if err := writeTextMetric(e.w, mf, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *textEncoder) Close() error {
+	return e.w.Flush()
+}
+
+func writeTextMetric(w io.Writer, mf *dto.MetricFamily, m *dto.Metric) error {
+	name := mf.GetName()
+	switch {
+	case m.Counter != nil:
+		return writeTextSample(w, name, "", m.Label, m.Counter.GetValue())
+	case m.Gauge != nil:
+		return writeTextSample(w, name, "", m.Label, m.Gauge.GetValue())
+	case m.Untyped != nil:
+		return writeTextSample(w, name, "", m.Label, m.Untyped.GetValue())
+	case m.Summary != nil:
+		s := m.Summary
+		for _, q := range s.Quantile {
+			if err := writeTextSample(w, name, "", appendLabel(m.Label, "quantile", formatFloat(q.GetQuantile())), q.GetValue()); err != nil {
+				return err
+			}
+		}
+		if err := writeTextSample(w, name+"_sum", "", m.Label, s.GetSampleSum()); err != nil {
+			return err
+		}
+		return writeTextSample(w, name+"_count", "", m.Label, float64(s.GetSampleCount()))
+	case m.Histogram != nil:
+		h := m.Histogram
+		for _, b := range h.Bucket {
+			if err := writeTextSample(w, name, "_bucket", appendLabel(m.Label, "le", formatFloat(b.GetUpperBound())), float64(b.GetCumulativeCount())); err != nil {
+				return err
+			}
+		}
+		if err := writeTextSample(w, name, "_bucket", appendLabel(m.Label, "le", "+Inf"), float64(h.GetSampleCount())); err != nil {
+			return err
+		}
+		if err := writeTextSample(w, name+"_sum", "", m.Label, h.GetSampleSum()); err != nil {
+			return err
+		}
+		return writeTextSample(w, name+"_count", "", m.Label, float64(h.GetSampleCount()))
+	}
+	return nil
+}
+
+func writeTextSample(w io.Writer, name, suffix string, labels []*dto.LabelPair, value float64) error {
+	_, err := fmt.Fprintf(w, "%s%s%s %s\n", name, suffix, formatLabels(labels), formatFloat(value))
+	return err
+}
+
+func formatLabels(labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels))
+	for _, lp := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%q", lp.GetName(), escapeString(lp.GetValue(), true)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func appendLabel(labels []*dto.LabelPair, name, value string) []*dto.LabelPair {
+	n, v := name, value
+	out := make([]*dto.LabelPair, len(labels), len(labels)+1)
+	copy(out, labels)
+	return append(out, &dto.LabelPair{Name: &n, Value: &v})
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func escapeString(s string, escapeDoubleQuote bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if escapeDoubleQuote {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+// openMetricsEncoder writes the OpenMetrics 1.0 text exposition format:
+// https://openmetrics.io/
+//
+// It emits "# UNIT" lines when a unit can be inferred from the metric name,
+// "_created" timestamps for Counter, Histogram, and Summary, exemplars on
+// Counter and Histogram bucket samples, and the mandatory "# EOF"
+// terminator once Close is called.
+type openMetricsEncoder struct {
+	w *bufio.Writer
+}
+
+// openMetricsUnitSuffixes lists the metric-name suffixes from which this
+// encoder infers a UNIT metadata line, per the OpenMetrics convention of
+// naming counters and gauges after their unit. "_total" names no unit and is
+// skipped by inferOpenMetricsUnit.
+var openMetricsUnitSuffixes = []string{
+	"_seconds", "_bytes", "_total", "_ratio", "_percent", "_celsius",
+}
+
+func (e *openMetricsEncoder) Encode(mf *dto.MetricFamily) error {
+	name := mf.GetName()
+	typ := strings.ToLower(mf.GetType().String())
+	omName := name
+	if mf.GetType() == dto.MetricType_COUNTER && !strings.HasSuffix(omName, "_total") {
+		omName += "_total"
+	}
+
+	fmt.Fprintf(e.w, "# HELP %s %s\n", omName, escapeString(mf.GetHelp(), false))
+	fmt.Fprintf(e.w, "# TYPE %s %s\n", omName, typ)
+	if unit := inferOpenMetricsUnit(name); unit != "" {
+		fmt.Fprintf(e.w, "# UNIT %s %s\n", omName, unit)
+	}
+
+	for _, m := range mf.Metric {
+		if err := e.writeMetric(mf, omName, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *openMetricsEncoder) writeMetric(mf *dto.MetricFamily, name string, m *dto.Metric) error {
+	switch {
+	case m.Counter != nil:
+		c := m.Counter
+		if err := e.writeSample(name, m.Label, c.GetValue(), c.Exemplar); err != nil {
+			return err
+		}
+		// The created-line companion to a counter is named after its bare
+		// name, not its "_total"-suffixed sample name (e.g. "foo_created",
+		// not "foo_total_created").
+		return e.writeCreated(strings.TrimSuffix(name, "_total"), m.Label, c.GetCreatedTimestamp())
+	case m.Gauge != nil:
+		return e.writeSample(name, m.Label, m.Gauge.GetValue(), nil)
+	case m.Untyped != nil:
+		return e.writeSample(name, m.Label, m.Untyped.GetValue(), nil)
+	case m.Summary != nil:
+		s := m.Summary
+		for _, q := range s.Quantile {
+			if err := e.writeSample(name, appendLabel(m.Label, "quantile", formatFloat(q.GetQuantile())), q.GetValue(), nil); err != nil {
+				return err
+			}
+		}
+		if err := e.writeSample(name+"_sum", m.Label, s.GetSampleSum(), nil); err != nil {
+			return err
+		}
+		if err := e.writeSample(name+"_count", m.Label, float64(s.GetSampleCount()), nil); err != nil {
+			return err
+		}
+		return e.writeCreated(name, m.Label, s.GetCreatedTimestamp())
+	case m.Histogram != nil:
+		h := m.Histogram
+		var cumCount uint64
+		for _, b := range h.Bucket {
+			cumCount = b.GetCumulativeCount()
+			if err := e.writeSample(name+"_bucket", appendLabel(m.Label, "le", formatFloat(b.GetUpperBound())), float64(cumCount), b.Exemplar); err != nil {
+				return err
+			}
+		}
+		if err := e.writeSample(name+"_bucket", appendLabel(m.Label, "le", "+Inf"), float64(h.GetSampleCount()), nil); err != nil {
+			return err
+		}
+		if err := e.writeSample(name+"_sum", m.Label, h.GetSampleSum(), nil); err != nil {
+			return err
+		}
+		if err := e.writeSample(name+"_count", m.Label, float64(h.GetSampleCount()), nil); err != nil {
+			return err
+		}
+		return e.writeCreated(name, m.Label, h.GetCreatedTimestamp())
+	}
+	return nil
+}
+
+func (e *openMetricsEncoder) writeSample(name string, labels []*dto.LabelPair, value float64, exemplar *dto.Exemplar) error {
+	if _, err := fmt.Fprintf(e.w, "%s%s %s", name, formatLabels(labels), formatFloat(value)); err != nil {
+		return err
+	}
+	if exemplar != nil {
+		if err := e.writeExemplar(exemplar); err != nil {
+			return err
+		}
+	}
+	_, err := e.w.WriteString("\n")
+	return err
+}
+
+func (e *openMetricsEncoder) writeExemplar(ex *dto.Exemplar) error {
+	_, err := fmt.Fprintf(e.w, " # %s %s", formatLabels(ex.Label), formatFloat(ex.GetValue()))
+	if err != nil {
+		return err
+	}
+	if ts := ex.GetTimestamp(); ts != nil {
+		secs := float64(ts.GetSeconds()) + float64(ts.GetNanos())/1e9
+		_, err = fmt.Fprintf(e.w, " %s", formatFloat(secs))
+	}
+	return err
+}
+
+func (e *openMetricsEncoder) writeCreated(name string, labels []*dto.LabelPair, ts *tspb.Timestamp) error {
+	if ts == nil {
+		return nil
+	}
+	secs := float64(ts.GetSeconds()) + float64(ts.GetNanos())/1e9
+	return e.writeSample(name+"_created", labels, secs, nil)
+}
+
+func (e *openMetricsEncoder) Close() error {
+	if _, err := e.w.WriteString("# EOF\n"); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func inferOpenMetricsUnit(name string) string {
+	for _, suffix := range openMetricsUnitSuffixes {
+		if suffix == "_total" {
+			continue
+		}
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimPrefix(suffix, "_")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestNegotiate(t *testing.T) {
+	scenarios := []struct {
+		accept string
+		want   Format
+	}{
+		{accept: "", want: FmtText},
+		{accept: "text/plain", want: FmtText},
+		{accept: "application/openmetrics-text", want: FmtOpenMetrics},
+		{accept: "application/openmetrics-text; version=1.0.0", want: FmtOpenMetrics},
+		{accept: "application/openmetrics-text; version=0.0.7", want: FmtText},
+		{accept: "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited", want: FmtProtoDelim},
+		{accept: "text/html, application/openmetrics-text", want: FmtOpenMetrics},
+	}
+	for _, s := range scenarios {
+		h := http.Header{}
+		if s.accept != "" {
+			h.Set(hdrAccept, s.accept)
+		}
+		if got := Negotiate(h); got != s.want {
+			t.Errorf("Negotiate(Accept: %q) = %q, want %q", s.accept, got, s.want)
+		}
+	}
+}
+
+func TestOpenMetricsEncoderCounter(t *testing.T) {
+	name, help := "http_requests", "Total number of requests."
+	typ := dto.MetricType_COUNTER
+	value := 5.0
+	mf := &dto.MetricFamily{
+		Name: &name,
+		Help: &help,
+		Type: &typ,
+		Metric: []*dto.Metric{
+			{Counter: &dto.Counter{Value: &value}},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, FmtOpenMetrics)
+	if err := enc.Encode(mf); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	got := buf.String()
+	want := "# HELP http_requests_total Total number of requests.\n" +
+		"# TYPE http_requests_total counter\n" +
+		"http_requests_total 5\n" +
+		"# EOF\n"
+	if got != want {
+		t.Errorf("openMetricsEncoder.Encode() = %q, want %q", got, want)
+	}
+}
+
+func TestOpenMetricsEncoderCounterAlreadySuffixed(t *testing.T) {
+	name, help := "http_requests_total", "Total number of requests."
+	typ := dto.MetricType_COUNTER
+	value := 1.0
+	mf := &dto.MetricFamily{
+		Name: &name,
+		Help: &help,
+		Type: &typ,
+		Metric: []*dto.Metric{
+			{Counter: &dto.Counter{Value: &value}},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, FmtOpenMetrics)
+	if err := enc.Encode(mf); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	got := buf.String()
+	if strings.Contains(got, "_total_total") {
+		t.Errorf("openMetricsEncoder.Encode() double-suffixed an already-suffixed counter name: %q", got)
+	}
+}
+
+func TestOpenMetricsEncoderCounterCreatedLine(t *testing.T) {
+	name, help := "http_requests", "Total number of requests."
+	typ := dto.MetricType_COUNTER
+	value := 5.0
+	created := &tspb.Timestamp{Seconds: 100}
+	mf := &dto.MetricFamily{
+		Name: &name,
+		Help: &help,
+		Type: &typ,
+		Metric: []*dto.Metric{
+			{Counter: &dto.Counter{Value: &value, CreatedTimestamp: created}},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, FmtOpenMetrics)
+	if err := enc.Encode(mf); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	got := buf.String()
+	if strings.Contains(got, "http_requests_total_created") {
+		t.Errorf("openMetricsEncoder.Encode() emitted http_requests_total_created, want http_requests_created: %q", got)
+	}
+	if !strings.Contains(got, "http_requests_created 100\n") {
+		t.Errorf("openMetricsEncoder.Encode() did not emit http_requests_created: %q", got)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	scenarios := []struct {
+		in   float64
+		want string
+	}{
+		{in: math.Inf(1), want: "+Inf"},
+		{in: math.Inf(-1), want: "-Inf"},
+		{in: math.NaN(), want: "NaN"},
+		{in: math.MaxFloat64, want: strconv.FormatFloat(math.MaxFloat64, 'g', -1, 64)},
+		{in: 1.5, want: "1.5"},
+	}
+	for _, s := range scenarios {
+		if got := formatFloat(s.in); got != s.want {
+			t.Errorf("formatFloat(%v) = %q, want %q", s.in, got, s.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefBuckets are the default Histogram buckets. The default buckets are
+// tailored to broadly measure the response time (in seconds) of a network
+// service. Most likely, however, you will be required to define buckets
+// customized to your use case.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Observer is the interface that wraps the Observe method, which is used by
+// Histogram and Summary to add observations.
+type Observer interface {
+	Observe(float64)
+}
+
+// Histogram counts individual observations from an event or sample stream in
+// configurable buckets. Similar to a Summary, it also provides a sum of
+// observations and an observation count.
+//
+// To create Histogram instances, use NewHistogram.
+type Histogram interface {
+	Metric
+	Collector
+	Observer
+
+	// ObserveWithExemplar replaces Observe and also replaces the currently
+	// saved exemplar (if any) with a new one, created from the provided
+	// value, the current time as timestamp, and the provided Labels.
+	// Exemplars are kept per bucket with a finite upper bound; an
+	// observation above the highest bound stores no exemplar.
+	ObserveWithExemplar(value float64, exemplar Labels)
+}
+
+// HistogramOpts bundles the options for creating a Histogram metric.
+type HistogramOpts struct {
+	Namespace   string
+	Subsystem   string
+	Name        string
+	Help        string
+	ConstLabels Labels
+
+	// Buckets defines the buckets into which observations are counted. Each
+	// element in the slice is the upper inclusive bound of a bucket. The
+	// values must be sorted in strictly increasing order. There is no need
+	// to add a highest bucket with +Inf bound, it will be added
+	// implicitly. The default value is DefBuckets.
+	Buckets []float64
+}
+
+// NewHistogram creates a new Histogram based on the provided HistogramOpts.
+func NewHistogram(opts HistogramOpts) Histogram {
+	desc := NewDesc(
+		BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		opts.Help,
+		nil,
+		opts.ConstLabels,
+	)
+	return newHistogram(desc, opts)
+}
+
+func newHistogram(desc *Desc, opts HistogramOpts, labelValues ...string) Histogram {
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = DefBuckets
+	}
+
+	h := &histogram{
+		desc:        desc,
+		upperBounds: opts.Buckets,
+		labelPairs:  makeLabelPairs(desc, labelValues),
+		createdTs:   time.Now(),
+	}
+	for i, ub := range h.upperBounds {
+		if i < len(h.upperBounds)-1 {
+			if ub >= h.upperBounds[i+1] {
+				panic(fmt.Errorf(
+					"histogram buckets must be in increasing order: %f >= %f",
+					ub, h.upperBounds[i+1],
+				))
+			}
+		} else {
+			if math.IsInf(ub, +1) {
+				h.upperBounds = h.upperBounds[:i]
+			}
+		}
+	}
+	h.counts = make([]uint64, len(h.upperBounds))
+	h.exemplars = make([]atomic.Value, len(h.upperBounds))
+
+	h.Init(h)
+	return h
+}
+
+type histogram struct {
+	// sumBits/count are the running sum and count of all observations,
+	// protected independently via CAS loops so Observe never blocks.
+	sumBits uint64
+	count   uint64
+
+	upperBounds []float64
+	counts      []uint64
+	exemplars   []atomic.Value // Each element is nil or a *dto.Exemplar.
+
+	selfCollector
+	desc *Desc
+
+	labelPairs []*dto.LabelPair
+	createdTs  time.Time
+}
+
+func (h *histogram) Desc() *Desc {
+	return h.desc
+}
+
+func (h *histogram) Observe(v float64) {
+	h.observe(v)
+}
+
+func (h *histogram) ObserveWithExemplar(v float64, e Labels) {
+	i := h.observe(v)
+	if e == nil {
+		return
+	}
+	ex, err := newExemplar(v, time.Now(), e)
+	if err != nil {
+		panic(err)
+	}
+	if i < len(h.exemplars) {
+		h.exemplars[i].Store(ex)
+	}
+}
+
+// observe records v and returns the index of the bucket it landed in (or
+// len(upperBounds) for the +Inf bucket), which is used by
+// ObserveWithExemplar to attach the exemplar to the right bucket.
+func (h *histogram) observe(v float64) int {
+	i := sort.SearchFloat64s(h.upperBounds, v)
+	if i < len(h.counts) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+	atomic.AddUint64(&h.count, 1)
+	for {
+		oldBits := atomic.LoadUint64(&h.sumBits)
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + v)
+		if atomic.CompareAndSwapUint64(&h.sumBits, oldBits, newBits) {
+			break
+		}
+	}
+	return i
+}
+
+func (h *histogram) Write(out *dto.Metric) error {
+	his := &dto.Histogram{
+		Bucket: make([]*dto.Bucket, len(h.upperBounds)),
+	}
+	his.SampleSum = proto64(math.Float64frombits(atomic.LoadUint64(&h.sumBits)))
+	his.SampleCount = protoU64(atomic.LoadUint64(&h.count))
+	his.CreatedTimestamp = &tspb.Timestamp{
+		Seconds: h.createdTs.Unix(),
+		Nanos:   int32(h.createdTs.Nanosecond()),
+	}
+
+	var cumCount uint64
+	for i, upperBound := range h.upperBounds {
+		cumCount += atomic.LoadUint64(&h.counts[i])
+		b := &dto.Bucket{
+			CumulativeCount: protoU64(cumCount),
+			UpperBound:      proto64(upperBound),
+		}
+		if e := h.exemplars[i].Load(); e != nil {
+			b.Exemplar = e.(*dto.Exemplar)
+		}
+		his.Bucket[i] = b
+	}
+
+	out.Histogram = his
+	out.Label = h.labelPairs
+	return nil
+}
+
+func protoU64(v uint64) *uint64 {
+	return &v
+}
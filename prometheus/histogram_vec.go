@@ -0,0 +1,122 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import "sync"
+
+// HistogramVec is a Collector that bundles a set of Histograms that all
+// share the same Desc, but have different values for their variable labels.
+// This is used if you want to count the same thing partitioned by various
+// dimensions, e.g. HTTP request latencies, partitioned by status code and
+// method.
+//
+// Create instances with NewHistogramVec.
+type HistogramVec struct {
+	desc *Desc
+	opts HistogramOpts
+
+	mtx      sync.RWMutex
+	children map[uint64]Histogram
+}
+
+// NewHistogramVec creates a new HistogramVec based on the provided
+// HistogramOpts and partitioned by the given label names.
+func NewHistogramVec(opts HistogramOpts, labelNames []string) *HistogramVec {
+	desc := NewDesc(
+		BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		opts.Help,
+		labelNames,
+		opts.ConstLabels,
+	)
+	return &HistogramVec{
+		desc:     desc,
+		opts:     opts,
+		children: map[uint64]Histogram{},
+	}
+}
+
+// GetMetricWithLabelValues returns the Histogram for the given slice of
+// label values (same order as the variable labels in Desc). If that
+// combination of label values is accessed for the first time, a new
+// Histogram is created.
+func (v *HistogramVec) GetMetricWithLabelValues(lvs ...string) (Observer, error) {
+	if err := validateLabelValues(lvs, len(v.desc.variableLabels)); err != nil {
+		return nil, err
+	}
+	h := hashNew()
+	for _, lv := range lvs {
+		h = hashAdd(h, lv)
+		h = hashAddByte(h, separatorByte)
+	}
+
+	v.mtx.RLock()
+	histogram, ok := v.children[h]
+	v.mtx.RUnlock()
+	if ok {
+		return histogram, nil
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if histogram, ok := v.children[h]; ok {
+		return histogram, nil
+	}
+	histogram = newHistogram(v.desc, v.opts, lvs...)
+	v.children[h] = histogram
+	return histogram, nil
+}
+
+// GetMetricWith works like GetMetricWithLabelValues but takes Labels instead
+// of a slice of label values, looking up each variable label by name.
+func (v *HistogramVec) GetMetricWith(labels Labels) (Observer, error) {
+	lvs := make([]string, len(v.desc.variableLabels))
+	for i, name := range v.desc.variableLabels {
+		lvs[i] = labels[name]
+	}
+	return v.GetMetricWithLabelValues(lvs...)
+}
+
+// With works like GetMetricWith, but panics where GetMetricWith would have
+// returned an error.
+func (v *HistogramVec) With(labels Labels) Observer {
+	o, err := v.GetMetricWith(labels)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// WithLabelValues works like GetMetricWithLabelValues, but panics where
+// GetMetricWithLabelValues would have returned an error.
+func (v *HistogramVec) WithLabelValues(lvs ...string) Observer {
+	o, err := v.GetMetricWithLabelValues(lvs...)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// Describe implements Collector.
+func (v *HistogramVec) Describe(ch chan<- *Desc) {
+	ch <- v.desc
+}
+
+// Collect implements Collector.
+func (v *HistogramVec) Collect(ch chan<- Metric) {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+	for _, h := range v.children {
+		ch <- h
+	}
+}
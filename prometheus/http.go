@@ -0,0 +1,118 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"log"
+	"net/http"
+)
+
+// Handler returns an HTTP handler for the DefaultGatherer, using the zero
+// value of HandlerOpts. It adds no instrumentation of its own. The preferred way to expose metrics over HTTP is to
+// use the Handler or HandlerFor function; Handler acts as a convenient
+// shortcut for the common case of exposing the DefaultGatherer.
+//
+// The Handler negotiates the wire format with the client based on the
+// request's Accept header: besides the classic Prometheus text format and
+// the protobuf delimited format, it now also understands
+// "application/openmetrics-text", in which case metrics are rendered in the
+// OpenMetrics 1.0 exposition format.
+func Handler() http.Handler {
+	return HandlerFor(DefaultGatherer, HandlerOpts{})
+}
+
+// HandlerOpts specifies options how to serve metrics via an http.Handler.
+// The zero value of HandlerOpts is a reasonable default.
+type HandlerOpts struct {
+	// ErrorLog specifies an optional logger for errors collecting and
+	// serving metrics. If nil, errors are not logged at all.
+	ErrorLog *log.Logger
+	// ErrorHandling defines how errors are handled. Note that errors are
+	// logged regardless of the configured ErrorHandling provided ErrorLog
+	// is not nil.
+	ErrorHandling HandlerErrorHandling
+	// DisableCompression is currently unused: this package does not
+	// implement response compression. It is kept for API compatibility
+	// with HandlerOpts in other Prometheus client libraries and has no
+	// effect on format negotiation, which always runs based on the
+	// request's Accept header.
+	DisableCompression bool
+}
+
+// HandlerErrorHandling defines how a Handler serving metrics will handle
+// errors.
+type HandlerErrorHandling int
+
+// These constants cause handlers serving metrics to behave as described if
+// errors are encountered.
+const (
+	// Serve an HTTP status code 500 upon the first error encountered.
+	// Report the error message in the body. This applies to errors while
+	// gathering; an error while encoding a metric family is only logged,
+	// and the remaining families are still served.
+	HTTPErrorOnError HandlerErrorHandling = iota
+	// Ignore errors and try to serve as many metrics as possible. However,
+	// if no metrics can be served, serve an HTTP status code 500 and the
+	// last error message in the body. Only use this in deliberate
+	// "best effort" metrics collection scenarios.
+	ContinueOnError
+	// Panic upon the first error encountered (useful for catching errors
+	// during testing, not recommended for most "real" programs).
+	PanicOnError
+)
+
+// HandlerFor returns an http.Handler for the provided Gatherer. The
+// behavior of the Handler is defined by the provided HandlerOpts. Thus,
+// HandlerFor is useful to create http.Handlers for custom Registries, with
+// custom (or no) instrumentation, and with custom HandlerOpts.
+func HandlerFor(reg Gatherer, opts HandlerOpts) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		mfs, err := reg.Gather()
+		if err != nil {
+			if opts.ErrorLog != nil {
+				opts.ErrorLog.Println("error gathering metrics:", err)
+			}
+			switch opts.ErrorHandling {
+			case PanicOnError:
+				panic(err)
+			case ContinueOnError:
+				if len(mfs) == 0 {
+					http.Error(rsp, "No metrics gathered, last error:\n\n"+err.Error(), http.StatusInternalServerError)
+					return
+				}
+			default:
+				http.Error(rsp, "An error has occurred during metrics gathering:\n\n"+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		format := Negotiate(req.Header)
+		rsp.Header().Set(hdrContentType, string(format))
+
+		enc := NewEncoder(rsp, format)
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				if opts.ErrorLog != nil {
+					opts.ErrorLog.Println("error encoding metric family:", err)
+				}
+				if opts.ErrorHandling == PanicOnError {
+					panic(err)
+				}
+			}
+		}
+		if err := enc.Close(); err != nil && opts.ErrorLog != nil {
+			opts.ErrorLog.Println("error closing encoder:", err)
+		}
+	})
+}
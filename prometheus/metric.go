@@ -0,0 +1,137 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Metric models a single sample value with its meta data being exported to
+// Prometheus. Implementations of Metric in this package are Gauge, Counter,
+// Histogram, and Summary.
+type Metric interface {
+	// Desc returns the descriptor for the Metric. This method idempotently
+	// returns the same descriptor throughout the lifetime of the Metric.
+	Desc() *Desc
+	// Write encodes the Metric into a "Metric" Protocol Buffer data
+	// transmission object.
+	//
+	// Implementers of custom Metric types must observe concurrency safety
+	// as reads of this metric may occur at any time, and any blocking
+	// occurs at the expense of total performance of rendering all
+	// registered metrics. Ideally, Metric implementations should support
+	// concurrent readers.
+	Write(out *dto.Metric) error
+}
+
+// Opts bundles the options for creating most Metric types. Each metric
+// implementation XXX has its own XXXOpts type, but in most cases, it is
+// just be an alias of this type (which might change when the requirement
+// arises.)
+//
+// It is mandatory to set Name and Help to a non-empty string. All other
+// fields are optional and can safely be left at their zero value.
+type Opts struct {
+	// Namespace, Subsystem, and Name are components of the fully-qualified
+	// name of the Metric (created by joining these components with
+	// "_"). Only Name is mandatory, the others merely help structuring the
+	// name. Note that the fully-qualified name of the metric must be a
+	// valid Prometheus metric name.
+	Namespace string
+	Subsystem string
+	Name      string
+
+	// Help provides information about this metric.
+	//
+	// Metrics with the same fully-qualified name must have the same Help
+	// string.
+	Help string
+
+	// ConstLabels are used to attach fixed labels to this metric. Metrics
+	// with the same fully-qualified name must have the same label names
+	// in their ConstLabels.
+	//
+	// Due to the way a Desc is loaded, it is usually not necessary to
+	// use this.
+	ConstLabels Labels
+}
+
+// BuildFQName joins the given three name components by "_". Empty name
+// components are ignored. If the name parameter itself is empty, an empty
+// string is returned, no matter what. Metric implementations included in
+// this package use this function internally to generate the fully-qualified
+// metric name from the name component in their Opts. Users can use it in
+// their own implementations of the Metric interface which do not embed
+// a type derived from an Opts type.
+func BuildFQName(namespace, subsystem, name string) string {
+	if name == "" {
+		return ""
+	}
+	switch {
+	case namespace != "" && subsystem != "":
+		return strings.Join([]string{namespace, subsystem, name}, "_")
+	case namespace != "":
+		return strings.Join([]string{namespace, name}, "_")
+	case subsystem != "":
+		return strings.Join([]string{subsystem, name}, "_")
+	}
+	return name
+}
+
+// Exemplar is a sample value with extra tracing information, attached to a
+// Counter or Histogram bucket observation. It is used to carry a reference
+// (e.g. a trace ID) from the instant an event happened to the exposition of
+// the aggregated metric.
+type Exemplar struct {
+	// Value is the observed value the Exemplar was recorded with. For an
+	// Exemplar attached to a Counter.AddWithExemplar call, this is the
+	// increment passed to the call.
+	Value float64
+	// Labels are attached to the Exemplar, e.g. a trace ID. The combined
+	// length of the label names and values must not exceed 128 UTF-8
+	// characters, as enforced by the OpenMetrics exposition format.
+	Labels Labels
+	// Timestamp is the time the Exemplar was recorded. The zero value
+	// means no timestamp was recorded.
+	Timestamp time.Time
+}
+
+// ExemplarAdder is implemented by Counters that offer the option of adding a
+// value to the Counter together with an exemplar. Its AddWithExemplar method
+// works like the Add method of the Counter interface but also replaces the
+// currently saved exemplar (if any) with a new one, created from the provided
+// value, the current time as timestamp, and the provided Labels. Empty
+// Labels will lead to a valid (label-less) exemplar. But if Labels is nil,
+// the current exemplar is left in place. AddWithExemplar panics if the value
+// is negative, if any of the provided labels are invalid, or if the provided
+// labels contain more than 128 runes in total.
+type ExemplarAdder interface {
+	AddWithExemplar(value float64, exemplar Labels)
+}
+
+// ExemplarObserver is implemented by Observers that offer the option of
+// observing a value together with an exemplar. Its ObserveWithExemplar
+// method works like the Observe method of the Observer interface but also
+// replaces the currently saved exemplar (if any) with a new one, created
+// from the provided value, the current time as timestamp, and the provided
+// Labels. Empty Labels will lead to a valid (label-less) exemplar. But if
+// Labels is nil, the current exemplar is left in place. ObserveWithExemplar
+// panics if any of the provided labels are invalid or if the provided labels
+// contain more than 128 runes in total.
+type ExemplarObserver interface {
+	ObserveWithExemplar(value float64, exemplar Labels)
+}
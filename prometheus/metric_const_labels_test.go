@@ -0,0 +1,54 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// TestConstLabelsWrittenToMetric guards against ConstLabels silently being
+// dropped from a Metric's dto.Metric.Label on Write, which each of these
+// constructors must populate from its Desc's constLabelPairs.
+func TestConstLabelsWrittenToMetric(t *testing.T) {
+	metrics := map[string]Metric{
+		"Counter": NewCounter(CounterOpts{
+			Name: "test_counter", Help: "help", ConstLabels: Labels{"foo": "bar"},
+		}),
+		"Gauge": NewGauge(GaugeOpts{
+			Name: "test_gauge", Help: "help", ConstLabels: Labels{"foo": "bar"},
+		}),
+		"Histogram": NewHistogram(HistogramOpts{
+			Name: "test_histogram", Help: "help", ConstLabels: Labels{"foo": "bar"},
+		}),
+		"Summary": NewSummary(SummaryOpts{
+			Name: "test_summary", Help: "help", ConstLabels: Labels{"foo": "bar"},
+		}),
+	}
+	for name, m := range metrics {
+		t.Run(name, func(t *testing.T) {
+			var out dto.Metric
+			if err := m.Write(&out); err != nil {
+				t.Fatalf("Write() returned error: %v", err)
+			}
+			if len(out.Label) != 1 {
+				t.Fatalf("got %d labels, want 1 (ConstLabels dropped)", len(out.Label))
+			}
+			if out.Label[0].GetName() != "foo" || out.Label[0].GetValue() != "bar" {
+				t.Errorf("got label %s=%s, want foo=bar", out.Label[0].GetName(), out.Label[0].GetValue())
+			}
+		})
+	}
+}
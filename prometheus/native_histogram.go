@@ -0,0 +1,357 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// NativeHistogramOpts bundles the options for creating a Histogram with a
+// sparse, exponentially-bucketed ("native") representation, as opposed to
+// the fixed-bucket representation created by NewHistogram.
+//
+// Native histograms need far fewer buckets than classic ones to achieve a
+// comparable relative error across a wide range of observed values, because
+// bucket boundaries are not fixed but derived from Schema.
+type NativeHistogramOpts struct {
+	Namespace   string
+	Subsystem   string
+	Name        string
+	Help        string
+	ConstLabels Labels
+
+	// Schema defines the resolution of the histogram. Each increment
+	// doubles the number of buckets per power of two, i.e. bucket
+	// boundaries are at base^i for base = 2^(2^-Schema). Schema can range
+	// from -4 (coarsest) to 8 (finest) and defaults to 3.
+	Schema int32
+
+	// MaxBucketNumber limits the total number of populated positive and
+	// negative buckets. If adding an observation would exceed this limit,
+	// the schema is reduced (adjacent buckets are merged, halving the
+	// bucket count) until the observation fits, or the histogram is reset
+	// if MinResetDuration has elapsed and growth is still uncontrollable.
+	// A value of 0 means no limit.
+	MaxBucketNumber uint32
+
+	// MinResetDuration is the minimum time that has to pass before the
+	// histogram is reset when MaxBucketNumber is exceeded and schema
+	// reduction alone cannot bring the bucket count back under the limit.
+	// A value of 0 disables the reset.
+	MinResetDuration time.Duration
+
+	// ZeroThreshold configures an unsigned zero bucket that absorbs all
+	// observations with an absolute value less than or equal to it. This
+	// is useful to avoid excessive bucket creation around zero, e.g. for
+	// observations of timer resolution noise. The zero value catches only
+	// observations that are exactly zero.
+	ZeroThreshold float64
+}
+
+const (
+	// NativeHistogramDefaultSchema is used if NativeHistogramOpts.Schema is
+	// left at its zero value.
+	NativeHistogramDefaultSchema = 3
+	nativeHistogramMinSchema     = -4
+	nativeHistogramMaxSchema     = 8
+)
+
+// NewNativeHistogram creates a new Histogram backed by sparse, exponential
+// buckets as configured by opts, rather than the fixed upper bounds used by
+// NewHistogram.
+func NewNativeHistogram(opts NativeHistogramOpts) Histogram {
+	desc := NewDesc(
+		BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		opts.Help,
+		nil,
+		opts.ConstLabels,
+	)
+	return newNativeHistogram(desc, opts)
+}
+
+func newNativeHistogram(desc *Desc, opts NativeHistogramOpts, labelValues ...string) *nativeHistogram {
+	schema := opts.Schema
+	if schema == 0 {
+		schema = NativeHistogramDefaultSchema
+	}
+	if schema > nativeHistogramMaxSchema {
+		schema = nativeHistogramMaxSchema
+	}
+	if schema < nativeHistogramMinSchema {
+		schema = nativeHistogramMinSchema
+	}
+	h := &nativeHistogram{
+		desc:             desc,
+		labelPairs:       makeLabelPairs(desc, labelValues),
+		schema:           schema,
+		zeroThreshold:    opts.ZeroThreshold,
+		maxBuckets:       opts.MaxBucketNumber,
+		minResetDuration: opts.MinResetDuration,
+		posBuckets:       map[int32]*uint64{},
+		negBuckets:       map[int32]*uint64{},
+		lastReset:        time.Now(),
+		createdTs:        time.Now(),
+	}
+	h.Init(h)
+	return h
+}
+
+// nativeHistogram is a Histogram implementation storing observations in
+// sparse, exponentially-spaced buckets indexed by
+// floor(log2(value) * 2^schema), with a dedicated zero bucket absorbing
+// |value| <= zeroThreshold.
+type nativeHistogram struct {
+	selfCollector
+	desc       *Desc
+	labelPairs []*dto.LabelPair
+	createdTs  time.Time
+
+	count   uint64
+	sumBits uint64
+
+	zeroThreshold float64
+	zeroCount     uint64
+
+	// mtx guards schema, lastReset, and structural changes to posBuckets
+	// and negBuckets (inserting a new bucket key, merging buckets on
+	// schema reduction, or resetting). Incrementing the count in an
+	// already-allocated bucket happens via atomic.AddUint64 without
+	// holding mtx.
+	mtx              sync.Mutex
+	schema           int32
+	maxBuckets       uint32
+	minResetDuration time.Duration
+	lastReset        time.Time
+	posBuckets       map[int32]*uint64
+	negBuckets       map[int32]*uint64
+}
+
+func (h *nativeHistogram) Desc() *Desc {
+	return h.desc
+}
+
+func (h *nativeHistogram) Observe(v float64) {
+	h.observe(v)
+}
+
+// ObserveWithExemplar exists to satisfy the Histogram interface. Unlike the
+// fixed bucket boundaries of a classic Histogram, sparse bucket keys can be
+// merged away by schema reduction, which would orphan a stored exemplar, so
+// the exemplar is accepted but not retained.
+func (h *nativeHistogram) ObserveWithExemplar(v float64, _ Labels) {
+	h.observe(v)
+}
+
+func (h *nativeHistogram) observe(v float64) {
+	atomic.AddUint64(&h.count, 1)
+	for {
+		oldBits := atomic.LoadUint64(&h.sumBits)
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + v)
+		if atomic.CompareAndSwapUint64(&h.sumBits, oldBits, newBits) {
+			break
+		}
+	}
+
+	if math.Abs(v) <= h.zeroThreshold {
+		atomic.AddUint64(&h.zeroCount, 1)
+		return
+	}
+
+	av := v
+	if v < 0 {
+		av = -v
+	}
+
+	h.mtx.Lock()
+	schemaBeforeReduction := h.schema
+	idx := nativeHistogramBucketIndex(av, schemaBeforeReduction)
+	if _, ok := h.bucketsFor(v)[idx]; !ok {
+		var zero uint64
+		h.bucketsFor(v)[idx] = &zero
+		h.maybeReduceResolutionLocked()
+	}
+	// maybeReduceResolutionLocked above may have decremented the schema and
+	// replaced h.posBuckets/h.negBuckets with a freshly merged map, which
+	// would orphan a bucket pointer captured before it ran. Mirror the same
+	// idx>>1 transform mergeNativeHistogramBuckets applies per schema step
+	// to find where our bucket ended up, rather than recomputing the index
+	// from av.
+	for s := schemaBeforeReduction; s > h.schema; s-- {
+		idx >>= 1
+	}
+	b, ok := h.bucketsFor(v)[idx]
+	if !ok {
+		// resetLocked has cleared every bucket together with count and
+		// sum; record this observation as the first one after the reset.
+		var zero uint64
+		b = &zero
+		h.bucketsFor(v)[idx] = b
+		atomic.AddUint64(&h.count, 1)
+		for {
+			oldBits := atomic.LoadUint64(&h.sumBits)
+			newBits := math.Float64bits(math.Float64frombits(oldBits) + v)
+			if atomic.CompareAndSwapUint64(&h.sumBits, oldBits, newBits) {
+				break
+			}
+		}
+	}
+	atomic.AddUint64(b, 1)
+	h.mtx.Unlock()
+}
+
+// bucketsFor returns the bucket map (positive or negative) that an
+// observation of v belongs to. It must be called with mtx held.
+func (h *nativeHistogram) bucketsFor(v float64) map[int32]*uint64 {
+	if v < 0 {
+		return h.negBuckets
+	}
+	return h.posBuckets
+}
+
+// nativeHistogramBucketIndex returns the index of the bucket that v (which
+// must be > 0) falls into for the given schema: buckets are bounded by
+// base^i <= v < base^(i+1) with base = 2^(2^-schema).
+func nativeHistogramBucketIndex(v float64, schema int32) int32 {
+	return int32(math.Floor(math.Log2(v) * math.Exp2(float64(schema))))
+}
+
+// maybeReduceResolutionLocked halves the resolution (decrements schema and
+// merges adjacent bucket pairs) until the total number of populated
+// buckets is at or under maxBuckets, or the schema has hit its floor. It
+// must be called with mtx held.
+func (h *nativeHistogram) maybeReduceResolutionLocked() {
+	if h.maxBuckets == 0 {
+		return
+	}
+	for uint32(len(h.posBuckets)+len(h.negBuckets)) > h.maxBuckets && h.schema > nativeHistogramMinSchema {
+		h.posBuckets = mergeNativeHistogramBuckets(h.posBuckets)
+		h.negBuckets = mergeNativeHistogramBuckets(h.negBuckets)
+		h.schema--
+	}
+	if uint32(len(h.posBuckets)+len(h.negBuckets)) > h.maxBuckets &&
+		h.minResetDuration > 0 && time.Since(h.lastReset) >= h.minResetDuration {
+		h.resetLocked()
+	}
+}
+
+// mergeNativeHistogramBuckets halves the resolution of buckets by merging
+// each pair of adjacent bucket indices (i.e. the two buckets that combine
+// into one when schema is decremented by one) into a single bucket at the
+// lower schema's index.
+func mergeNativeHistogramBuckets(buckets map[int32]*uint64) map[int32]*uint64 {
+	merged := make(map[int32]*uint64, len(buckets)/2+1)
+	for idx, count := range buckets {
+		newIdx := idx >> 1
+		n := atomic.LoadUint64(count)
+		if existing, ok := merged[newIdx]; ok {
+			n += atomic.LoadUint64(existing)
+		}
+		merged[newIdx] = &n
+	}
+	return merged
+}
+
+// resetLocked snapshots the current state is implicit (the caller already
+// wrote out whatever it needed via Write before reduction became
+// uncontrollable) and clears the histogram so future observations start
+// fresh. It must be called with mtx held.
+func (h *nativeHistogram) resetLocked() {
+	atomic.StoreUint64(&h.count, 0)
+	atomic.StoreUint64(&h.sumBits, 0)
+	atomic.StoreUint64(&h.zeroCount, 0)
+	h.posBuckets = map[int32]*uint64{}
+	h.negBuckets = map[int32]*uint64{}
+	h.lastReset = time.Now()
+}
+
+func (h *nativeHistogram) Write(out *dto.Metric) error {
+	h.mtx.Lock()
+	schema := h.schema
+	posSpans, posDeltas := spansAndDeltas(h.posBuckets)
+	negSpans, negDeltas := spansAndDeltas(h.negBuckets)
+	h.mtx.Unlock()
+
+	his := &dto.Histogram{
+		SampleCount:   protoU64(atomic.LoadUint64(&h.count)),
+		SampleSum:     proto64(math.Float64frombits(atomic.LoadUint64(&h.sumBits))),
+		Schema:        protoI32(schema),
+		ZeroThreshold: proto64(h.zeroThreshold),
+		ZeroCount:     protoU64(atomic.LoadUint64(&h.zeroCount)),
+		PositiveSpan:  posSpans,
+		PositiveDelta: posDeltas,
+		NegativeSpan:  negSpans,
+		NegativeDelta: negDeltas,
+		CreatedTimestamp: &tspb.Timestamp{
+			Seconds: h.createdTs.Unix(),
+			Nanos:   int32(h.createdTs.Nanosecond()),
+		},
+	}
+	out.Histogram = his
+	out.Label = h.labelPairs
+	return nil
+}
+
+// spansAndDeltas converts a sparse index -> count map into the span/delta
+// encoding used by the exposition formats: spans describe contiguous runs
+// of populated bucket indices (with a gap length to the previous span), and
+// deltas are the count of each bucket relative to the previous one in
+// iteration order (the first delta is relative to zero).
+func spansAndDeltas(buckets map[int32]*uint64) ([]*dto.BucketSpan, []int64) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	indexes := make([]int32, 0, len(buckets))
+	for idx := range buckets {
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	var spans []*dto.BucketSpan
+	var deltas []int64
+	var prevIdx int32
+	var prevCount int64
+	first := true
+	for _, idx := range indexes {
+		count := int64(atomic.LoadUint64(buckets[idx]))
+		if first {
+			spans = append(spans, &dto.BucketSpan{Offset: protoI32(idx), Length: protoU32(1)})
+			deltas = append(deltas, count)
+			first = false
+		} else if idx == prevIdx+1 {
+			spans[len(spans)-1].Length = protoU32(spans[len(spans)-1].GetLength() + 1)
+			deltas = append(deltas, count-prevCount)
+		} else {
+			spans = append(spans, &dto.BucketSpan{Offset: protoI32(idx - prevIdx - 1), Length: protoU32(1)})
+			deltas = append(deltas, count-prevCount)
+		}
+		prevIdx = idx
+		prevCount = count
+	}
+	return spans, deltas
+}
+
+func protoI32(v int32) *int32 {
+	return &v
+}
+
+func protoU32(v uint32) *uint32 {
+	return &v
+}
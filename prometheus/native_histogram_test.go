@@ -0,0 +1,128 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func bucketsFromCounts(counts map[int32]uint64) map[int32]*uint64 {
+	buckets := make(map[int32]*uint64, len(counts))
+	for idx, c := range counts {
+		c := c
+		buckets[idx] = &c
+	}
+	return buckets
+}
+
+func countsFromBuckets(buckets map[int32]*uint64) map[int32]uint64 {
+	counts := make(map[int32]uint64, len(buckets))
+	for idx, c := range buckets {
+		counts[idx] = atomic.LoadUint64(c)
+	}
+	return counts
+}
+
+func TestMergeNativeHistogramBuckets(t *testing.T) {
+	// Each pair of adjacent indices (..., -4,-3), (-2,-1), (0,1), (2,3), ...
+	// must merge onto the lower index shifted right by one.
+	in := bucketsFromCounts(map[int32]uint64{
+		-4: 1, -3: 1,
+		-2: 1, -1: 1,
+		0: 1, 1: 1,
+		2: 1, 3: 1,
+	})
+	want := map[int32]uint64{
+		-2: 2, // from -4, -3
+		-1: 2, // from -2, -1
+		0:  2, // from 0, 1
+		1:  2, // from 2, 3
+	}
+	got := countsFromBuckets(mergeNativeHistogramBuckets(in))
+	if len(got) != len(want) {
+		t.Fatalf("mergeNativeHistogramBuckets() = %v, want %v", got, want)
+	}
+	for idx, count := range want {
+		if got[idx] != count {
+			t.Errorf("mergeNativeHistogramBuckets()[%d] = %d, want %d", idx, got[idx], count)
+		}
+	}
+}
+
+func TestNativeHistogramObserveResolutionReduction(t *testing.T) {
+	h := newNativeHistogram(NewDesc("test", "test histogram", nil, nil), NativeHistogramOpts{
+		Schema:          NativeHistogramDefaultSchema,
+		MaxBucketNumber: 4,
+	})
+
+	for i := 1; i <= 20; i++ {
+		h.Observe(float64(i))
+	}
+
+	h.mtx.Lock()
+	numBuckets := uint32(len(h.posBuckets) + len(h.negBuckets))
+	schema := h.schema
+	h.mtx.Unlock()
+
+	if numBuckets > 4 {
+		t.Errorf("got %d populated buckets, want at most MaxBucketNumber=4", numBuckets)
+	}
+	if schema >= NativeHistogramDefaultSchema {
+		t.Errorf("got schema %d, want it reduced below the default %d after exceeding MaxBucketNumber", schema, NativeHistogramDefaultSchema)
+	}
+}
+
+// TestNativeHistogramCountMatchesBucketSumAcrossReduction guards against a
+// regression where an observation that both creates a new bucket and pushes
+// the bucket count over MaxBucketNumber (triggering a resolution reduction)
+// would increment a bucket pointer orphaned by the reduction's merge,
+// silently dropping the observation.
+func TestNativeHistogramCountMatchesBucketSumAcrossReduction(t *testing.T) {
+	h := newNativeHistogram(NewDesc("test", "test histogram", nil, nil), NativeHistogramOpts{
+		MaxBucketNumber: 2,
+	})
+	h.Observe(1)
+	h.Observe(2)
+	h.Observe(100)
+
+	h.mtx.Lock()
+	var sum uint64
+	for _, b := range h.posBuckets {
+		sum += atomic.LoadUint64(b)
+	}
+	for _, b := range h.negBuckets {
+		sum += atomic.LoadUint64(b)
+	}
+	h.mtx.Unlock()
+
+	if sum != h.count {
+		t.Errorf("sum of bucket counts = %d, want %d (h.count)", sum, h.count)
+	}
+}
+
+func TestNativeHistogramObserveNegativeAndPositive(t *testing.T) {
+	h := newNativeHistogram(NewDesc("test", "test histogram", nil, nil), NativeHistogramOpts{})
+	h.Observe(1)
+	h.Observe(-1)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if len(h.posBuckets) != 1 {
+		t.Errorf("got %d positive buckets, want 1", len(h.posBuckets))
+	}
+	if len(h.negBuckets) != 1 {
+		t.Errorf("got %d negative buckets, want 1", len(h.negBuckets))
+	}
+}
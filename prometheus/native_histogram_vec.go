@@ -0,0 +1,104 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import "sync"
+
+// NativeHistogramVec is a Collector that bundles a set of native Histograms
+// that all share the same Desc, but have different values for their
+// variable labels. This is used if you want to count the same thing
+// partitioned by various dimensions, e.g. the duration of an HTTP request,
+// partitioned by status code and method.
+//
+// NativeHistogramVec embeds no Metric itself, but acts as a factory for
+// Histograms partitioned by the given label values, each backed by the
+// sparse bucket representation of NewNativeHistogram.
+type NativeHistogramVec struct {
+	desc *Desc
+	opts NativeHistogramOpts
+
+	mtx      sync.RWMutex
+	children map[uint64]*nativeHistogram
+}
+
+// NewNativeHistogramVec creates a new NativeHistogramVec based on the
+// provided NativeHistogramOpts and partitioned by the given label names.
+func NewNativeHistogramVec(opts NativeHistogramOpts, labelNames []string) *NativeHistogramVec {
+	desc := NewDesc(
+		BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		opts.Help,
+		labelNames,
+		opts.ConstLabels,
+	)
+	return &NativeHistogramVec{
+		desc:     desc,
+		opts:     opts,
+		children: map[uint64]*nativeHistogram{},
+	}
+}
+
+// GetMetricWithLabelValues returns the Histogram for the given slice of
+// label values (same order as the variable labels in Desc). If that
+// combination of label values is accessed for the first time, a new
+// Histogram is created.
+func (v *NativeHistogramVec) GetMetricWithLabelValues(lvs ...string) (Histogram, error) {
+	if err := validateLabelValues(lvs, len(v.desc.variableLabels)); err != nil {
+		return nil, err
+	}
+	h := hashNew()
+	for _, lv := range lvs {
+		h = hashAdd(h, lv)
+		h = hashAddByte(h, separatorByte)
+	}
+
+	v.mtx.RLock()
+	histogram, ok := v.children[h]
+	v.mtx.RUnlock()
+	if ok {
+		return histogram, nil
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if histogram, ok := v.children[h]; ok {
+		return histogram, nil
+	}
+	histogram = newNativeHistogram(v.desc, v.opts, lvs...)
+	v.children[h] = histogram
+	return histogram, nil
+}
+
+// WithLabelValues works like GetMetricWithLabelValues, but panics where
+// GetMetricWithLabelValues would have returned an error.
+func (v *NativeHistogramVec) WithLabelValues(lvs ...string) Histogram {
+	h, err := v.GetMetricWithLabelValues(lvs...)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Describe implements Collector.
+func (v *NativeHistogramVec) Describe(ch chan<- *Desc) {
+	ch <- v.desc
+}
+
+// Collect implements Collector.
+func (v *NativeHistogramVec) Collect(ch chan<- Metric) {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+	for _, h := range v.children {
+		ch <- h
+	}
+}
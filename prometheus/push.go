@@ -0,0 +1,109 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Push triggers a metric collection by the default registry and pushes all
+// collected metrics to the Pushgateway specified by url, grouped by the
+// provided job name and, if instance is not empty, by an "instance" label.
+// It uses HTTP PUT, like PushCollectors.
+//
+// Deprecated: Push is retained for backwards compatibility. The push
+// subpackage offers more flexibility (custom HTTP client, TLS, retries,
+// cancellation) via push.New(url, job).Collector(...).Push().
+func Push(job, instance, url string) error {
+	return doPush(job, instance, url, http.MethodPut, DefaultGatherer)
+}
+
+// PushAdd works like Push, but only previously pushed metrics with the same
+// name (and the same job and other grouping labels) are replaced.
+//
+// Deprecated: see Push.
+func PushAdd(job, instance, url string) error {
+	return doPush(job, instance, url, http.MethodPost, DefaultGatherer)
+}
+
+// PushCollectors pushes the metrics collected by the provided Collectors. It
+// uses HTTP PUT, which replaces any previously pushed metrics with the same
+// job and instance.
+//
+// Deprecated: see Push.
+func PushCollectors(job, instance, url string, collectors ...Collector) error {
+	return pushAddCollectors(job, instance, url, http.MethodPut, collectors...)
+}
+
+// PushAddCollectors works like PushCollectors, but uses HTTP POST, which
+// only replaces previously pushed metrics with the same name as the
+// provided ones and leaves all others in place.
+//
+// Deprecated: see Push.
+func PushAddCollectors(job, instance, url string, collectors ...Collector) error {
+	return pushAddCollectors(job, instance, url, http.MethodPost, collectors...)
+}
+
+func pushAddCollectors(job, instance, pushURL, method string, collectors ...Collector) error {
+	reg := NewRegistry()
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return doPush(job, instance, pushURL, method, reg)
+}
+
+func doPush(job, instance, pushURL, method string, g Gatherer) error {
+	if !strings.HasPrefix(pushURL, "http://") && !strings.HasPrefix(pushURL, "https://") {
+		pushURL = "http://" + pushURL
+	}
+	pushURL = fmt.Sprintf("%s/metrics/job/%s", pushURL, job)
+	if instance != "" {
+		pushURL = fmt.Sprintf("%s/instance/%s", pushURL, instance)
+	}
+
+	mfs, err := g.Gather()
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, FmtProtoDelim)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, pushURL, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(hdrContentType, string(FmtProtoDelim))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("unexpected status code %d while pushing to %s", resp.StatusCode, pushURL)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefaultRegisterer and DefaultGatherer are the implementations of the
+// Registerer and Gatherer interface a number of convenience functions in
+// this package act on. Initially, both variables point to the same
+// Registry, which has no Collectors registered.
+var (
+	defaultRegistry              = NewRegistry()
+	DefaultRegisterer Registerer = defaultRegistry
+	DefaultGatherer   Gatherer   = defaultRegistry
+)
+
+// Registerer is the interface for the part of a registry in charge of
+// registering and unregistering. Users of custom registries should use
+// Registerer as type for registration purposes (rather than the Registry
+// type directly).
+type Registerer interface {
+	// Register registers a new Collector to be included in metrics
+	// collection. It returns an error if the descriptors provided by the
+	// Collector are invalid or if they - in combination with descriptors of
+	// already registered Collectors - do not fulfill the consistency and
+	// uniqueness criteria described in the documentation of Desc.
+	Register(Collector) error
+	// MustRegister works like Register but panics where Register would have
+	// returned an error.
+	MustRegister(...Collector)
+	// Unregister unregisters the Collector that equals the Collector passed
+	// in as an argument.
+	Unregister(Collector) bool
+}
+
+// Gatherer is the interface for the part of a registry in charge of
+// gathering the collected metrics into a number of MetricFamilies.
+type Gatherer interface {
+	// Gather calls the Collect method of the registered Collectors and then
+	// gathers the collected metrics into a lexicographically sorted slice
+	// of MetricFamily protobufs.
+	Gather() ([]*dto.MetricFamily, error)
+}
+
+// Registry registers Prometheus collectors, collects their metrics, and
+// gathers them into MetricFamilies for exposition.
+type Registry struct {
+	mtx        sync.RWMutex
+	collectors map[Collector]struct{}
+	descIDs    map[uint64]struct{}
+	dimHashes  map[string]uint64
+}
+
+// NewRegistry creates a new vanilla Registry without any Collectors
+// pre-registered.
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: map[Collector]struct{}{},
+		descIDs:    map[uint64]struct{}{},
+		dimHashes:  map[string]uint64{},
+	}
+}
+
+// Register implements Registerer.
+func (r *Registry) Register(c Collector) error {
+	descChan := make(chan *Desc, capDescChan)
+	go func() {
+		c.Describe(descChan)
+		close(descChan)
+	}()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var newDescIDs []uint64
+	var firstErr error
+	for desc := range descChan {
+		if firstErr != nil {
+			continue // Drain the channel so the feeding goroutine above cannot block on a full send.
+		}
+		if desc.err != nil {
+			firstErr = desc.err
+			continue
+		}
+		if _, exists := r.descIDs[desc.id]; exists {
+			firstErr = fmt.Errorf("duplicate metrics collector registration attempted for descriptor %s", desc)
+			continue
+		}
+		if dimHash, exists := r.dimHashes[desc.fqName]; exists {
+			if dimHash != desc.dimHash {
+				firstErr = fmt.Errorf("a previously registered descriptor with the same fully-qualified name as %s has different label names or a different help string", desc)
+				continue
+			}
+		} else {
+			r.dimHashes[desc.fqName] = desc.dimHash
+		}
+		newDescIDs = append(newDescIDs, desc.id)
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if len(newDescIDs) == 0 {
+		return fmt.Errorf("no registerable descriptors found in %v", c)
+	}
+	for _, id := range newDescIDs {
+		r.descIDs[id] = struct{}{}
+	}
+	r.collectors[c] = struct{}{}
+	return nil
+}
+
+// MustRegister implements Registerer.
+func (r *Registry) MustRegister(cs ...Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister implements Registerer.
+func (r *Registry) Unregister(c Collector) bool {
+	descChan := make(chan *Desc, capDescChan)
+	go func() {
+		c.Describe(descChan)
+		close(descChan)
+	}()
+	var descIDs []uint64
+	for desc := range descChan {
+		descIDs = append(descIDs, desc.id)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.collectors[c]; !ok {
+		return false
+	}
+	delete(r.collectors, c)
+	for _, id := range descIDs {
+		delete(r.descIDs, id)
+	}
+	return true
+}
+
+const capDescChan = 32
+
+// Gather implements Gatherer.
+func (r *Registry) Gather() ([]*dto.MetricFamily, error) {
+	r.mtx.RLock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mtx.RUnlock()
+
+	families := map[string]*dto.MetricFamily{}
+	metricChan := make(chan Metric, capDescChan)
+	var wg sync.WaitGroup
+	wg.Add(len(collectors))
+	for _, c := range collectors {
+		go func(c Collector) {
+			c.Collect(metricChan)
+			wg.Done()
+		}(c)
+	}
+	go func() {
+		wg.Wait()
+		close(metricChan)
+	}()
+
+	for m := range metricChan {
+		desc := m.Desc()
+		dtoMetric := &dto.Metric{}
+		if err := m.Write(dtoMetric); err != nil {
+			return nil, err
+		}
+		mf, ok := families[desc.fqName]
+		if !ok {
+			name, help := desc.fqName, desc.help
+			mf = &dto.MetricFamily{Name: &name, Help: &help}
+			mf.Type = metricTypeFor(dtoMetric)
+			families[desc.fqName] = mf
+		}
+		mf.Metric = append(mf.Metric, dtoMetric)
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		result = append(result, mf)
+	}
+	sort.Sort(metricFamilySorter(result))
+	return result, nil
+}
+
+func metricTypeFor(m *dto.Metric) *dto.MetricType {
+	var t dto.MetricType
+	switch {
+	case m.Counter != nil:
+		t = dto.MetricType_COUNTER
+	case m.Gauge != nil:
+		t = dto.MetricType_GAUGE
+	case m.Histogram != nil:
+		t = dto.MetricType_HISTOGRAM
+	case m.Summary != nil:
+		t = dto.MetricType_SUMMARY
+	default:
+		t = dto.MetricType_UNTYPED
+	}
+	return &t
+}
+
+type metricFamilySorter []*dto.MetricFamily
+
+func (s metricFamilySorter) Len() int           { return len(s) }
+func (s metricFamilySorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s metricFamilySorter) Less(i, j int) bool { return s[i].GetName() < s[j].GetName() }
+
+// Gatherers is a slice of Gatherer instances that implements the Gatherer
+// interface itself by merging the results of calling Gather on each of its
+// elements, keeping the combined result lexicographically sorted by metric
+// family name. It is useful to combine multiple Gatherers (e.g. several
+// custom Registries) for a single Handler or push.
+type Gatherers []Gatherer
+
+// Gather implements Gatherer.
+func (gs Gatherers) Gather() ([]*dto.MetricFamily, error) {
+	byName := map[string]*dto.MetricFamily{}
+	for _, g := range gs {
+		mfs, err := g.Gather()
+		if err != nil {
+			return nil, err
+		}
+		for _, mf := range mfs {
+			if existing, ok := byName[mf.GetName()]; ok {
+				existing.Metric = append(existing.Metric, mf.Metric...)
+				continue
+			}
+			byName[mf.GetName()] = mf
+		}
+	}
+	result := make([]*dto.MetricFamily, 0, len(byName))
+	for _, mf := range byName {
+		result = append(result, mf)
+	}
+	sort.Sort(metricFamilySorter(result))
+	return result, nil
+}
+
+// Register registers the provided Collector with the DefaultRegisterer.
+func Register(c Collector) error {
+	return DefaultRegisterer.Register(c)
+}
+
+// MustRegister registers the provided Collectors with the
+// DefaultRegisterer and panics if any error occurs.
+func MustRegister(cs ...Collector) {
+	DefaultRegisterer.MustRegister(cs...)
+}
+
+// Unregister removes the registration of the provided Collector from the
+// DefaultRegisterer.
+func Unregister(c Collector) bool {
+	return DefaultRegisterer.Unregister(c)
+}
@@ -0,0 +1,110 @@
+// Copyright 2014 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryRegisterAndGather(t *testing.T) {
+	reg := NewRegistry()
+	c := NewCounter(CounterOpts{Name: "test_counter", Help: "help"})
+	c.Add(3)
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Register() returned error: %v", err)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() returned error: %v", err)
+	}
+	if len(mfs) != 1 {
+		t.Fatalf("got %d metric families, want 1", len(mfs))
+	}
+	if got := mfs[0].GetName(); got != "test_counter" {
+		t.Errorf("got name %q, want test_counter", got)
+	}
+	if got := mfs[0].Metric[0].Counter.GetValue(); got != 3 {
+		t.Errorf("got counter value %v, want 3", got)
+	}
+}
+
+func TestRegistryRejectsDuplicateRegistration(t *testing.T) {
+	reg := NewRegistry()
+	c1 := NewCounter(CounterOpts{Name: "test_counter", Help: "help"})
+	c2 := NewCounter(CounterOpts{Name: "test_counter", Help: "help"})
+
+	if err := reg.Register(c1); err != nil {
+		t.Fatalf("first Register() returned error: %v", err)
+	}
+	err := reg.Register(c2)
+	if err == nil {
+		t.Fatal("second Register() with the same name returned no error")
+	}
+	if !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("got error %q, want it to mention a duplicate registration", err)
+	}
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	reg := NewRegistry()
+	c := NewCounter(CounterOpts{Name: "test_counter", Help: "help"})
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Register() returned error: %v", err)
+	}
+	if !reg.Unregister(c) {
+		t.Fatal("Unregister() = false, want true for a registered Collector")
+	}
+	if reg.Unregister(c) {
+		t.Fatal("second Unregister() = true, want false")
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() returned error: %v", err)
+	}
+	if len(mfs) != 0 {
+		t.Errorf("got %d metric families after Unregister, want 0", len(mfs))
+	}
+}
+
+// manyDescCollector describes more descriptors than capDescChan, all
+// invalid, to guard against Register leaking the Describe-feeding goroutine
+// by returning before draining descChan.
+type manyDescCollector struct{}
+
+func (manyDescCollector) Describe(ch chan<- *Desc) {
+	for i := 0; i < capDescChan*2; i++ {
+		ch <- NewInvalidDesc(errors.New("invalid desc for test"))
+	}
+}
+
+func (manyDescCollector) Collect(chan<- Metric) {}
+
+func TestRegistryRegisterDrainsDescChan(t *testing.T) {
+	reg := NewRegistry()
+	done := make(chan struct{})
+	go func() {
+		reg.Register(manyDescCollector{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Register() did not return, the Describe-feeding goroutine likely blocked on a full descChan")
+	}
+}
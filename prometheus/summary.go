@@ -0,0 +1,121 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Summary captures individual observations and summarizes them in a
+// manner similar to traditional summary statistics: 1. sum of
+// observations, 2. observation count, 3. rank estimations.
+//
+// To create Summary instances, use NewSummary.
+type Summary interface {
+	Metric
+	Collector
+	Observer
+}
+
+// SummaryOpts bundles the options for creating a Summary metric.
+type SummaryOpts struct {
+	Namespace   string
+	Subsystem   string
+	Name        string
+	Help        string
+	ConstLabels Labels
+
+	// Objectives defines the quantile ranks to expose, each mapped to an
+	// absolute error. This package does not implement streaming quantile
+	// estimation: every quantile listed here is reported with the value
+	// NaN, and the error values are not used. Only the running sum and
+	// count are tracked, for the exposition format's _sum and _count
+	// series.
+	Objectives map[float64]float64
+}
+
+// NewSummary creates a new Summary based on the provided SummaryOpts.
+func NewSummary(opts SummaryOpts) Summary {
+	desc := NewDesc(
+		BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		opts.Help,
+		nil,
+		opts.ConstLabels,
+	)
+	s := &summary{
+		desc:       desc,
+		labelPairs: desc.constLabelPairs,
+		objectives: opts.Objectives,
+		createdTs:  time.Now(),
+	}
+	s.Init(s)
+	return s
+}
+
+type summary struct {
+	sumBits uint64
+	count   uint64
+
+	mtx        sync.Mutex
+	objectives map[float64]float64
+
+	selfCollector
+	desc       *Desc
+	labelPairs []*dto.LabelPair
+	createdTs  time.Time
+}
+
+func (s *summary) Desc() *Desc {
+	return s.desc
+}
+
+func (s *summary) Observe(v float64) {
+	atomic.AddUint64(&s.count, 1)
+	for {
+		oldBits := atomic.LoadUint64(&s.sumBits)
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + v)
+		if atomic.CompareAndSwapUint64(&s.sumBits, oldBits, newBits) {
+			return
+		}
+	}
+}
+
+func (s *summary) Write(out *dto.Metric) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sum := &dto.Summary{
+		SampleSum:   proto64(math.Float64frombits(atomic.LoadUint64(&s.sumBits))),
+		SampleCount: protoU64(atomic.LoadUint64(&s.count)),
+		CreatedTimestamp: &tspb.Timestamp{
+			Seconds: s.createdTs.Unix(),
+			Nanos:   int32(s.createdTs.Nanosecond()),
+		},
+	}
+	for q := range s.objectives {
+		sum.Quantile = append(sum.Quantile, &dto.Quantile{
+			Quantile: proto64(q),
+			Value:    proto64(math.NaN()),
+		})
+	}
+	out.Summary = sum
+	out.Label = s.labelPairs
+	return nil
+}
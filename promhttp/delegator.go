@@ -0,0 +1,143 @@
+// Copyright 2016 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promhttp
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// delegator wraps an http.ResponseWriter to observe the status code written
+// to it (defaulting to http.StatusOK if WriteHeader is never called
+// explicitly, matching net/http's own behavior) and the number of bytes
+// written.
+type delegator interface {
+	http.ResponseWriter
+
+	Status() int
+	Written() int64
+}
+
+type responseWriterDelegator struct {
+	http.ResponseWriter
+
+	status      int
+	written     int64
+	wroteHeader bool
+}
+
+func (d *responseWriterDelegator) WriteHeader(code int) {
+	d.status = code
+	d.wroteHeader = true
+	d.ResponseWriter.WriteHeader(code)
+}
+
+func (d *responseWriterDelegator) Write(b []byte) (int, error) {
+	if !d.wroteHeader {
+		d.WriteHeader(http.StatusOK)
+	}
+	n, err := d.ResponseWriter.Write(b)
+	d.written += int64(n)
+	return n, err
+}
+
+func (d *responseWriterDelegator) Status() int {
+	if !d.wroteHeader {
+		return http.StatusOK
+	}
+	return d.status
+}
+
+func (d *responseWriterDelegator) Written() int64 {
+	return d.written
+}
+
+type flusherDelegator struct{ *responseWriterDelegator }
+type hijackerDelegator struct{ *responseWriterDelegator }
+type closeNotifierDelegator struct{ *responseWriterDelegator }
+
+func (d flusherDelegator) Flush() {
+	d.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (d hijackerDelegator) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return d.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// http.CloseNotifier is deprecated but still implemented by many
+// ResponseWriters; preserve the interface upgrade for callers that still
+// rely on it.
+//
+//lint:ignore SA1019 see above.
+func (d closeNotifierDelegator) CloseNotify() <-chan bool {
+	return d.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// pickDelegator wraps w in a delegator that upgrades to exactly the optional
+// interfaces (http.Flusher, http.Hijacker, http.CloseNotifier) that w itself
+// implements, so that code further up the middleware chain (e.g. a
+// WebSocket handler checking for http.Hijacker) keeps working.
+func pickDelegator(w http.ResponseWriter) delegator {
+	d := &responseWriterDelegator{ResponseWriter: w}
+
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+	_, isCloseNotifier := w.(http.CloseNotifier)
+
+	switch {
+	case isFlusher && isHijacker && isCloseNotifier:
+		return struct {
+			*responseWriterDelegator
+			http.Flusher
+			http.Hijacker
+			http.CloseNotifier
+		}{d, flusherDelegator{d}, hijackerDelegator{d}, closeNotifierDelegator{d}}
+	case isFlusher && isHijacker:
+		return struct {
+			*responseWriterDelegator
+			http.Flusher
+			http.Hijacker
+		}{d, flusherDelegator{d}, hijackerDelegator{d}}
+	case isFlusher && isCloseNotifier:
+		return struct {
+			*responseWriterDelegator
+			http.Flusher
+			http.CloseNotifier
+		}{d, flusherDelegator{d}, closeNotifierDelegator{d}}
+	case isHijacker && isCloseNotifier:
+		return struct {
+			*responseWriterDelegator
+			http.Hijacker
+			http.CloseNotifier
+		}{d, hijackerDelegator{d}, closeNotifierDelegator{d}}
+	case isFlusher:
+		return struct {
+			*responseWriterDelegator
+			http.Flusher
+		}{d, flusherDelegator{d}}
+	case isHijacker:
+		return struct {
+			*responseWriterDelegator
+			http.Hijacker
+		}{d, hijackerDelegator{d}}
+	case isCloseNotifier:
+		return struct {
+			*responseWriterDelegator
+			http.CloseNotifier
+		}{d, closeNotifierDelegator{d}}
+	default:
+		return d
+	}
+}
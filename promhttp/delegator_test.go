@@ -0,0 +1,98 @@
+// Copyright 2016 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promhttp
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// plainResponseWriter implements only http.ResponseWriter.
+type plainResponseWriter struct {
+	http.ResponseWriter
+}
+
+// flusherResponseWriter additionally implements http.Flusher.
+type flusherResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (flusherResponseWriter) Flush() {}
+
+// fullResponseWriter additionally implements http.Flusher, http.Hijacker,
+// and http.CloseNotifier.
+type fullResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (fullResponseWriter) Flush() {}
+func (fullResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+func (fullResponseWriter) CloseNotify() <-chan bool { return nil }
+
+func TestPickDelegatorStatusAndWritten(t *testing.T) {
+	rec := httptest.NewRecorder()
+	d := pickDelegator(rec)
+
+	if _, err := d.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if got := d.Status(); got != http.StatusOK {
+		t.Errorf("Status() = %d, want %d (implicit 200 on first Write)", got, http.StatusOK)
+	}
+	if got := d.Written(); got != 5 {
+		t.Errorf("Written() = %d, want 5", got)
+	}
+}
+
+func TestPickDelegatorExplicitStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	d := pickDelegator(rec)
+	d.WriteHeader(http.StatusTeapot)
+	if got := d.Status(); got != http.StatusTeapot {
+		t.Errorf("Status() = %d, want %d", got, http.StatusTeapot)
+	}
+}
+
+func TestPickDelegatorInterfaceUpgrades(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		w            http.ResponseWriter
+		wantFlusher  bool
+		wantHijacker bool
+		wantNotifier bool
+	}{
+		{name: "plain", w: plainResponseWriter{httptest.NewRecorder()}},
+		{name: "flusher only", w: flusherResponseWriter{httptest.NewRecorder()}, wantFlusher: true},
+		{name: "full", w: fullResponseWriter{httptest.NewRecorder()}, wantFlusher: true, wantHijacker: true, wantNotifier: true},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			d := pickDelegator(s.w)
+			if _, ok := d.(http.Flusher); ok != s.wantFlusher {
+				t.Errorf("implements http.Flusher = %v, want %v", ok, s.wantFlusher)
+			}
+			if _, ok := d.(http.Hijacker); ok != s.wantHijacker {
+				t.Errorf("implements http.Hijacker = %v, want %v", ok, s.wantHijacker)
+			}
+			if _, ok := d.(http.CloseNotifier); ok != s.wantNotifier { //nolint:staticcheck // exercising the deprecated upgrade path
+				t.Errorf("implements http.CloseNotifier = %v, want %v", ok, s.wantNotifier)
+			}
+		})
+	}
+}
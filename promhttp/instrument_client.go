@@ -0,0 +1,282 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promhttp
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// roundTripperFunc implements http.RoundTripper with a plain function,
+// analogous to http.HandlerFunc, so Instrument* below can wrap next.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// InstrumentRoundTripperInFlight wraps the given RoundTripper to observe the
+// number of in-flight requests with the given Gauge.
+func InstrumentRoundTripperInFlight(g prometheus.Gauge, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		g.Inc()
+		defer g.Dec()
+		return next.RoundTrip(r)
+	})
+}
+
+// InstrumentRoundTripperCounter wraps the given RoundTripper to increment
+// counter once the round trip completes, labeled by "code" and/or "method"
+// if counter was created with those variable labels. The number of requests
+// that error out (and hence never produce a status code) is not counted.
+func InstrumentRoundTripperCounter(counter *prometheus.CounterVec, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(r)
+		if err != nil {
+			return resp, err
+		}
+		counter.With(codeMethodLabels(resp.StatusCode, r.Method)).Inc()
+		return resp, err
+	})
+}
+
+// InstrumentRoundTripperDuration wraps the given RoundTripper to observe
+// the request duration in obs, labeled by "code" and/or "method" if obs was
+// created with those variable labels.
+func InstrumentRoundTripperDuration(obs *prometheus.HistogramVec, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(r)
+		if err != nil {
+			return resp, err
+		}
+		obs.With(codeMethodLabels(resp.StatusCode, r.Method)).Observe(time.Since(start).Seconds())
+		return resp, err
+	})
+}
+
+// InstrumentTrace holds the histograms to be instrumented by
+// InstrumentRoundTripperTrace. Each field is optional; a nil Observer skips
+// that event.
+type InstrumentTrace struct {
+	GotConn              prometheus.Observer
+	PutIdleConn          prometheus.Observer
+	GotFirstResponseByte prometheus.Observer
+	Got100Continue       prometheus.Observer
+	DNSStart             prometheus.Observer
+	DNSDone              prometheus.Observer
+	ConnectStart         prometheus.Observer
+	ConnectDone          prometheus.Observer
+	TLSHandshakeStart    prometheus.Observer
+	TLSHandshakeDone     prometheus.Observer
+	WroteHeaders         prometheus.Observer
+	Wait100Continue      prometheus.Observer
+	WroteRequest         prometheus.Observer
+}
+
+// InstrumentRoundTripperTraceOnce is like InstrumentRoundTripperDuration,
+// but instead instruments the individual connection-lifecycle events of a
+// single round trip (DNS lookup, TLS handshake, etc.) via an
+// httptrace.ClientTrace, recording the time elapsed since the round trip
+// started into the relevant Observer in it.
+//
+// If the request already carries a ClientTrace (e.g. because an outer
+// InstrumentRoundTripperTraceOnce is wrapping an inner one), the existing
+// hooks are composed with, rather than replaced by, this call's hooks (by
+// httptrace.WithClientTrace), so each configured event still fires exactly
+// once per round trip no matter how many Instrument*TraceOnce decorators
+// are chained.
+func InstrumentRoundTripperTraceOnce(it *InstrumentTrace, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		since := func() float64 { return time.Since(start).Seconds() }
+
+		trace := &httptrace.ClientTrace{
+			GotConn: func(httptrace.GotConnInfo) {
+				observe(it.GotConn, since())
+			},
+			PutIdleConn: func(error) {
+				observe(it.PutIdleConn, since())
+			},
+			GotFirstResponseByte: func() {
+				observe(it.GotFirstResponseByte, since())
+			},
+			Got100Continue: func() {
+				observe(it.Got100Continue, since())
+			},
+			DNSStart: func(httptrace.DNSStartInfo) {
+				observe(it.DNSStart, since())
+			},
+			DNSDone: func(httptrace.DNSDoneInfo) {
+				observe(it.DNSDone, since())
+			},
+			ConnectStart: func(string, string) {
+				observe(it.ConnectStart, since())
+			},
+			ConnectDone: func(string, string, error) {
+				observe(it.ConnectDone, since())
+			},
+			TLSHandshakeStart: func() {
+				observe(it.TLSHandshakeStart, since())
+			},
+			TLSHandshakeDone: func(tls.ConnectionState, error) {
+				observe(it.TLSHandshakeDone, since())
+			},
+			WroteHeaders: func() {
+				observe(it.WroteHeaders, since())
+			},
+			Wait100Continue: func() {
+				observe(it.Wait100Continue, since())
+			},
+			WroteRequest: func(httptrace.WroteRequestInfo) {
+				observe(it.WroteRequest, since())
+			},
+		}
+		r = r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+		return next.RoundTrip(r)
+	})
+}
+
+// composeClientTrace returns a ClientTrace that, for every hook set on
+// either old or new, calls old's hook (if any) followed by new's hook (if
+// any).
+func composeClientTrace(old, next *httptrace.ClientTrace) *httptrace.ClientTrace {
+	if old == nil {
+		return next
+	}
+	merged := *old
+	merged.GotConn = chainGotConn(old.GotConn, next.GotConn)
+	merged.PutIdleConn = chainErr(old.PutIdleConn, next.PutIdleConn)
+	merged.GotFirstResponseByte = chainVoid(old.GotFirstResponseByte, next.GotFirstResponseByte)
+	merged.Got100Continue = chainVoid(old.Got100Continue, next.Got100Continue)
+	merged.DNSStart = chainDNSStart(old.DNSStart, next.DNSStart)
+	merged.DNSDone = chainDNSDone(old.DNSDone, next.DNSDone)
+	merged.ConnectStart = chainConnect(old.ConnectStart, next.ConnectStart)
+	merged.ConnectDone = chainConnectDone(old.ConnectDone, next.ConnectDone)
+	merged.TLSHandshakeStart = chainVoid(old.TLSHandshakeStart, next.TLSHandshakeStart)
+	merged.TLSHandshakeDone = chainTLSDone(old.TLSHandshakeDone, next.TLSHandshakeDone)
+	merged.WroteHeaders = chainVoid(old.WroteHeaders, next.WroteHeaders)
+	merged.Wait100Continue = chainVoid(old.Wait100Continue, next.Wait100Continue)
+	merged.WroteRequest = chainWroteRequest(old.WroteRequest, next.WroteRequest)
+	return &merged
+}
+
+func chainVoid(a, b func()) func() {
+	return func() {
+		if a != nil {
+			a()
+		}
+		if b != nil {
+			b()
+		}
+	}
+}
+
+func chainErr(a, b func(error)) func(error) {
+	return func(err error) {
+		if a != nil {
+			a(err)
+		}
+		if b != nil {
+			b(err)
+		}
+	}
+}
+
+func chainGotConn(a, b func(httptrace.GotConnInfo)) func(httptrace.GotConnInfo) {
+	return func(info httptrace.GotConnInfo) {
+		if a != nil {
+			a(info)
+		}
+		if b != nil {
+			b(info)
+		}
+	}
+}
+
+func chainDNSStart(a, b func(httptrace.DNSStartInfo)) func(httptrace.DNSStartInfo) {
+	return func(info httptrace.DNSStartInfo) {
+		if a != nil {
+			a(info)
+		}
+		if b != nil {
+			b(info)
+		}
+	}
+}
+
+func chainDNSDone(a, b func(httptrace.DNSDoneInfo)) func(httptrace.DNSDoneInfo) {
+	return func(info httptrace.DNSDoneInfo) {
+		if a != nil {
+			a(info)
+		}
+		if b != nil {
+			b(info)
+		}
+	}
+}
+
+func chainConnect(a, b func(string, string)) func(string, string) {
+	return func(network, addr string) {
+		if a != nil {
+			a(network, addr)
+		}
+		if b != nil {
+			b(network, addr)
+		}
+	}
+}
+
+func chainConnectDone(a, b func(string, string, error)) func(string, string, error) {
+	return func(network, addr string, err error) {
+		if a != nil {
+			a(network, addr, err)
+		}
+		if b != nil {
+			b(network, addr, err)
+		}
+	}
+}
+
+func chainTLSDone(a, b func(tls.ConnectionState, error)) func(tls.ConnectionState, error) {
+	return func(state tls.ConnectionState, err error) {
+		if a != nil {
+			a(state, err)
+		}
+		if b != nil {
+			b(state, err)
+		}
+	}
+}
+
+func chainWroteRequest(a, b func(httptrace.WroteRequestInfo)) func(httptrace.WroteRequestInfo) {
+	return func(info httptrace.WroteRequestInfo) {
+		if a != nil {
+			a(info)
+		}
+		if b != nil {
+			b(info)
+		}
+	}
+}
+
+func observe(o prometheus.Observer, v float64) {
+	if o != nil {
+		o.Observe(v)
+	}
+}
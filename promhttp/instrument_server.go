@@ -0,0 +1,123 @@
+// Copyright 2016 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package promhttp provides tooling to instrument net/http handlers and
+// round-trippers. Each Instrument* function wraps an existing http.Handler
+// (or http.RoundTripper) and records into a CounterVec, HistogramVec, or
+// Gauge supplied by the caller, so the caller retains full control over the
+// metric's name, help text, buckets, and which of the "code" and "method"
+// labels it wants to partition by. No "handler" label value is filled in: a
+// metric created with a "handler" variable label gets the empty string for
+// it.
+package promhttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InstrumentHandlerInFlight wraps the given http.Handler to observe the
+// number of in-flight requests with the given Gauge. An inbound request
+// increments g for the duration of the call to next.ServeHTTP.
+func InstrumentHandlerInFlight(g prometheus.Gauge, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Inc()
+		defer g.Dec()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// InstrumentHandlerDuration wraps the given http.Handler to observe the
+// request duration in obs, labeled by "code" and/or "method" if obs was
+// created with those variable labels.
+func InstrumentHandlerDuration(obs *prometheus.HistogramVec, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		d := pickDelegator(w)
+		next.ServeHTTP(d, r)
+		obs.With(codeMethodLabels(d.Status(), r.Method)).Observe(time.Since(now).Seconds())
+	}
+}
+
+// InstrumentHandlerCounter wraps the given http.Handler to increment counter
+// once the request completes, labeled by "code" and/or "method" if counter
+// was created with those variable labels.
+func InstrumentHandlerCounter(counter *prometheus.CounterVec, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d := pickDelegator(w)
+		next.ServeHTTP(d, r)
+		counter.With(codeMethodLabels(d.Status(), r.Method)).Inc()
+	}
+}
+
+// InstrumentHandlerRequestSize wraps the given http.Handler to observe the
+// approximate request size in obs, labeled by "code" and/or "method" if obs
+// was created with those variable labels. The size is the summed length of
+// the URL, method, protocol, header names and values, and host, plus the
+// Content-Length of the inbound request when it is known.
+func InstrumentHandlerRequestSize(obs *prometheus.HistogramVec, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d := pickDelegator(w)
+		next.ServeHTTP(d, r)
+		size := computeApproximateRequestSize(r)
+		obs.With(codeMethodLabels(d.Status(), r.Method)).Observe(float64(size))
+	}
+}
+
+// InstrumentHandlerResponseSize wraps the given http.Handler to observe the
+// response size (the number of bytes written to the ResponseWriter) in obs,
+// labeled by "code" and/or "method" if obs was created with those variable
+// labels.
+func InstrumentHandlerResponseSize(obs *prometheus.HistogramVec, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := pickDelegator(w)
+		next.ServeHTTP(d, r)
+		obs.With(codeMethodLabels(d.Status(), r.Method)).Observe(float64(d.Written()))
+	})
+}
+
+// codeMethodLabels builds the Labels map InstrumentHandler* functions pass
+// to With. Only the label names the caller actually configured on the
+// metric are looked up by With/GetMetricWith, so passing both "code" and
+// "method" unconditionally is safe regardless of which subset (if any) of
+// them the metric was created with.
+func codeMethodLabels(code int, method string) prometheus.Labels {
+	return prometheus.Labels{
+		"code":   strconv.Itoa(code),
+		"method": strings.ToLower(method),
+	}
+}
+
+func computeApproximateRequestSize(r *http.Request) int {
+	s := 0
+	if r.URL != nil {
+		s += len(r.URL.String())
+	}
+	s += len(r.Method)
+	s += len(r.Proto)
+	for name, values := range r.Header {
+		s += len(name)
+		for _, v := range values {
+			s += len(v)
+		}
+	}
+	s += len(r.Host)
+	if r.ContentLength != -1 {
+		s += int(r.ContentLength)
+	}
+	return s
+}
@@ -0,0 +1,337 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package push provides a flexible client for pushing metrics to a
+// Prometheus Pushgateway, as a more capable alternative to the free
+// functions in the prometheus package (prometheus.Push, PushAdd,
+// PushCollectors, ...), which have no support for timeouts, custom HTTP
+// clients, authentication, or retries.
+//
+// The entry point is New, which returns a *Pusher that can be configured
+// with a fluent API before calling Push, Add, or Delete:
+//
+//	err := push.New("http://example.org:9091", "my_job").
+//	    Grouping("instance", "my_instance").
+//	    Client(&http.Client{Timeout: 5 * time.Second}).
+//	    Collector(myCollector).
+//	    Push()
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func base64URLEncode(s string) string {
+	return base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString([]byte(s))
+}
+
+const (
+	pushMetricsPath = "/metrics/job/%s"
+
+	// defaultMaxRetries is used if Retry is not called.
+	defaultMaxRetries = 0
+)
+
+// Pusher manages a push to the Pushgateway. Use New to create one, then
+// configure it with the fluent setters below before calling Push, Add, or
+// Delete.
+type Pusher struct {
+	url, job string
+	grouping map[string]string
+
+	gatherers  prometheus.Gatherers
+	registerer prometheus.Registerer
+
+	client  *http.Client
+	header  http.Header
+	format  prometheus.Format
+	useAuth bool
+	user    string
+	pass    string
+
+	maxRetries int
+	backoff    func(attempt int) time.Duration
+
+	err error
+}
+
+// New creates a new Pusher to push to the provided URL (e.g.
+// "http://pushgateway.example.org:9091") with the given job name. Further
+// grouping labels can be added with Grouping; by default the metrics are
+// grouped by job only.
+func New(url, job string) *Pusher {
+	return &Pusher{
+		url:        url,
+		job:        job,
+		grouping:   map[string]string{},
+		gatherers:  prometheus.Gatherers{},
+		registerer: prometheus.NewRegistry(),
+		client:     &http.Client{},
+		header:     http.Header{},
+		format:     prometheus.FmtProtoDelim,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+}
+
+func defaultBackoff(attempt int) time.Duration {
+	return time.Duration(1<<uint(attempt)) * 100 * time.Millisecond
+}
+
+// Client sets a custom http.Client to use for pushing. The default is
+// &http.Client{}.
+func (p *Pusher) Client(c *http.Client) *Pusher {
+	p.client = c
+	return p
+}
+
+// Grouping sets a label name/value pair to further distinguish this
+// Pusher's metrics from others pushed to the same Pushgateway under the
+// same job. It can be called repeatedly to add further grouping labels.
+// The "job" and "instance" names are reserved for use as the job name and
+// as a grouping label, respectively, and Grouping panics if name equals
+// "job".
+func (p *Pusher) Grouping(name, value string) *Pusher {
+	if name == "job" {
+		panic(errors.New(`grouping must not contain a "job" label`))
+	}
+	p.grouping[name] = value
+	return p
+}
+
+// BasicAuth configures HTTP Basic Auth for the push request using the
+// provided username and password.
+func (p *Pusher) BasicAuth(username, password string) *Pusher {
+	p.useAuth = true
+	p.user = username
+	p.pass = password
+	return p
+}
+
+// Header sets an additional HTTP header to send with the push request. It
+// can be called repeatedly to set multiple headers.
+func (p *Pusher) Header(key, value string) *Pusher {
+	p.header.Set(key, value)
+	return p
+}
+
+// Format sets the wire format used to encode the metrics, e.g.
+// prometheus.FmtProtoDelim (the default) or prometheus.FmtOpenMetrics.
+func (p *Pusher) Format(format prometheus.Format) *Pusher {
+	p.format = format
+	return p
+}
+
+// Retry configures the Pusher to retry the push up to maxRetries times
+// with exponential backoff if the Pushgateway responds with a 5xx status
+// code. A request that fails to be sent at all is not retried. The default
+// is no retries.
+func (p *Pusher) Retry(maxRetries int) *Pusher {
+	p.maxRetries = maxRetries
+	return p
+}
+
+// Collector adds a Collector to be included in the push. Multiple
+// Collectors can be added by calling Collector repeatedly.
+func (p *Pusher) Collector(c prometheus.Collector) *Pusher {
+	if err := p.registerer.Register(c); err != nil && p.err == nil {
+		p.err = err
+	}
+	return p
+}
+
+// Gatherer adds a Gatherer to be included in the push, e.g. a custom
+// *prometheus.Registry. Multiple Gatherers can be added by calling
+// Gatherer repeatedly.
+func (p *Pusher) Gatherer(g prometheus.Gatherer) *Pusher {
+	p.gatherers = append(p.gatherers, g)
+	return p
+}
+
+// Push sends the collected metrics to the Pushgateway, replacing any
+// previously pushed metrics with the same job and grouping labels (HTTP
+// PUT).
+func (p *Pusher) Push() error {
+	return p.push(context.Background(), http.MethodPut)
+}
+
+// PushContext works like Push but observes context cancellation and
+// deadlines, both for the initial request and for any retries.
+func (p *Pusher) PushContext(ctx context.Context) error {
+	return p.push(ctx, http.MethodPut)
+}
+
+// Add sends the collected metrics to the Pushgateway, replacing only
+// previously pushed metrics with the same name as the sent ones and leaving
+// all others in place (HTTP POST).
+func (p *Pusher) Add() error {
+	return p.push(context.Background(), http.MethodPost)
+}
+
+// AddContext works like Add but observes context cancellation and
+// deadlines.
+func (p *Pusher) AddContext(ctx context.Context) error {
+	return p.push(ctx, http.MethodPost)
+}
+
+// Delete deletes metrics from the Pushgateway for this Pusher's job and
+// grouping labels (HTTP DELETE). No metrics need to have been added via
+// Collector or Gatherer beforehand.
+func (p *Pusher) Delete() error {
+	return p.deleteContext(context.Background())
+}
+
+// DeleteContext works like Delete but observes context cancellation and
+// deadlines.
+func (p *Pusher) DeleteContext(ctx context.Context) error {
+	return p.deleteContext(ctx)
+}
+
+func (p *Pusher) push(ctx context.Context, method string) error {
+	if p.err != nil {
+		return p.err
+	}
+	gatherers := append(prometheus.Gatherers{p.registerer.(prometheus.Gatherer)}, p.gatherers...)
+	mfs, err := gatherers.Gather()
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	enc := prometheus.NewEncoder(buf, p.format)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	return p.doWithRetry(ctx, method, buf.Bytes())
+}
+
+func (p *Pusher) deleteContext(ctx context.Context) error {
+	return p.doWithRetry(ctx, http.MethodDelete, nil)
+}
+
+func (p *Pusher) doWithRetry(ctx context.Context, method string, body []byte) error {
+	var lastErr error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(p.backoff(attempt - 1)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err := p.do(ctx, method, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		var se *statusError
+		if !errors.As(err, &se) || se.code < 500 {
+			return err
+		}
+	}
+	return lastErr
+}
+
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d while pushing: %s", e.code, e.body)
+}
+
+func (p *Pusher) do(ctx context.Context, method string, body []byte) error {
+	pushURL, err := p.fullURL()
+	if err != nil {
+		return err
+	}
+
+	var reader *bytes.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, pushURL, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", string(p.format))
+	}
+	for k, vs := range p.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if p.useAuth {
+		req.SetBasicAuth(p.user, p.pass)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return &statusError{code: resp.StatusCode, body: string(body)}
+	}
+	return nil
+}
+
+func (p *Pusher) fullURL() (string, error) {
+	base := p.url
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "http://" + base
+	}
+	base = strings.TrimRight(base, "/")
+
+	if strings.Contains(p.job, "/") {
+		return "", fmt.Errorf("job name %q must not contain %q", p.job, "/")
+	}
+	urlPath := base + fmt.Sprintf(pushMetricsPath, url.PathEscape(p.job))
+	names := make([]string, 0, len(p.grouping))
+	for name := range p.grouping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := p.grouping[name]
+		if strings.Contains(value, "/") {
+			urlPath += fmt.Sprintf("/%s@base64/%s", name, base64URLEncode(value))
+			continue
+		}
+		urlPath += fmt.Sprintf("/%s/%s", name, url.PathEscape(value))
+	}
+	return urlPath, nil
+}
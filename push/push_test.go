@@ -0,0 +1,128 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPusherFullURL(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		pusher   *Pusher
+		wantPath string
+		wantErr  bool
+	}{
+		{
+			name:     "job only",
+			pusher:   New("example.org:9091", "my_job"),
+			wantPath: "http://example.org:9091/metrics/job/my_job",
+		},
+		{
+			name:     "with grouping",
+			pusher:   New("http://example.org:9091", "my_job").Grouping("instance", "my_instance"),
+			wantPath: "http://example.org:9091/metrics/job/my_job/instance/my_instance",
+		},
+		{
+			name:     "grouping value with slash is base64 encoded",
+			pusher:   New("http://example.org:9091", "my_job").Grouping("path", "a/b"),
+			wantPath: "http://example.org:9091/metrics/job/my_job/path@base64/" + base64URLEncode("a/b"),
+		},
+		{
+			name:    "job with slash is rejected",
+			pusher:  New("http://example.org:9091", "a/b"),
+			wantErr: true,
+		},
+		{
+			name: "multiple grouping labels are ordered by name",
+			pusher: New("http://example.org:9091", "my_job").
+				Grouping("zone", "z1").
+				Grouping("instance", "my_instance"),
+			wantPath: "http://example.org:9091/metrics/job/my_job/instance/my_instance/zone/z1",
+		},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			got, err := s.pusher.fullURL()
+			if s.wantErr {
+				if err == nil {
+					t.Fatalf("fullURL() returned no error, want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fullURL() returned error: %v", err)
+			}
+			if got != s.wantPath {
+				t.Errorf("fullURL() = %q, want %q", got, s.wantPath)
+			}
+		})
+	}
+}
+
+func TestPusherRetriesOn5xxAndSucceeds(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL, "my_job").Retry(3)
+	p.backoff = func(int) time.Duration { return 0 }
+
+	if err := p.Push(); err != nil {
+		t.Fatalf("Push() returned error: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts, want 3", got)
+	}
+}
+
+func TestPusherDoesNotRetryOn4xx(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("bad request"))
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL, "my_job").Retry(3)
+	p.backoff = func(int) time.Duration { return 0 }
+
+	err := p.Push()
+	if err == nil {
+		t.Fatal("Push() returned no error, want one")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("got %d attempts, want 1 (no retry on 4xx)", got)
+	}
+	var se *statusError
+	if !errors.As(err, &se) {
+		t.Fatalf("error is not a *statusError: %v", err)
+	}
+	if !strings.Contains(se.body, "bad request") {
+		t.Errorf("statusError.body = %q, want it to contain the response body", se.body)
+	}
+}